@@ -0,0 +1,20 @@
+/*
+Copyright © 2023 Jonathan Taylor <jonrtaylor12@gmail.com>
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// calibrateCmd groups the calibration routines
+var calibrateCmd = &cobra.Command{
+	Use:   "calibrate",
+	Short: "calibrates the pipette and the deck",
+	Long:  `Runs the calibration routines and writes what they find back into the deck file.`,
+}
+
+func init() {
+	rootCmd.AddCommand(calibrateCmd)
+}
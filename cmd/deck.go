@@ -0,0 +1,210 @@
+/*
+Copyright © 2023 Jonathan Taylor <jonrtaylor12@gmail.com>
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	pb "pipbot/pipbot"
+)
+
+var calCorners int
+
+// errAborted is returned when the user quits jogging.
+var errAborted = errors.New("calibration aborted")
+
+// deckCmd represents the calibrate deck command
+var deckCmd = &cobra.Command{
+	Use:   "deck [matrix...]",
+	Short: "finds where each matrix sits by jogging the gantry to it",
+	Long: `Moves over each matrix in turn, every one on the deck unless some are named, and lets you
+jog the pipette tip onto the center of its A1 well and then the well in the opposite
+corner. The spacings and rotation worked out from them are saved to the deck file after
+each matrix. With --corners 3 the last well of the first row is measured too, and a full
+transform is saved instead, which also takes up skew and a plate that is not level.
+
+Keys:
+  a d, ← →   X - / +
+  s w, ↓ ↑   Y - / +
+  f r        Z - / +
+  1 2 3      step size 0.1, 1 or 10 mm
+  enter      record the position
+  q          quit`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if deckFile == "" {
+			return errors.New("calibrate deck needs a --deck file to save to")
+		}
+		if calCorners != 2 && calCorners != 3 {
+			return fmt.Errorf("--corners must be 2 or 3, not %v", calCorners)
+		}
+		bot, err := newBot()
+		if err != nil {
+			return err
+		}
+		defer bot.Close()
+
+		matrices := bot.Layout.Matrices
+		if len(args) > 0 {
+			matrices = nil
+			for _, a := range args {
+				m, err := bot.Layout.Matrix(a)
+				if err != nil {
+					return err
+				}
+				matrices = append(matrices, m)
+			}
+		}
+		if restore, err := pb.Cbreak(os.Stdin); err == nil {
+			defer restore()
+		}
+		j := &jogger{bot: bot, keys: bufio.NewReader(cmd.InOrStdin()), out: cmd.OutOrStdout(), step: 1}
+		if err = bot.Home(); err != nil {
+			return err
+		}
+		for _, m := range matrices {
+			if err = j.calibrate(m); err != nil {
+				return err
+			}
+		}
+		return bot.GoTo(bot.Commanded.WithZ(pb.TipBoxClear))
+	},
+}
+
+// jogger moves the bot around from key presses.
+type jogger struct {
+	bot  *pb.PipBot
+	keys *bufio.Reader
+	out  io.Writer
+	step int
+}
+
+// calibrate measures m and saves what it finds.
+func (j *jogger) calibrate(m *pb.Matrix) error {
+	a1, err := j.measure(m, 0, 0)
+	if err != nil {
+		return err
+	}
+	var end *pb.Position
+	if calCorners == 3 && m.Rows > 1 && m.Columns > 1 {
+		if end, err = j.measure(m, 0, m.Columns-1); err != nil {
+			return err
+		}
+	}
+	far, err := j.measure(m, m.Rows-1, m.Columns-1)
+	if err != nil {
+		return err
+	}
+	fit, err := pb.FitGrid(m, *a1, *far, end)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(j.out, "%v: home X%v Y%v Z%v, row space %v, col space %v, rotation %v°, skew %v°\n",
+		m.Name, fit.Home.X, fit.Home.Y, fit.Home.Z, fit.RowSpace, fit.ColSpace, fit.Rotation, fit.Skew)
+	if t := fit.Transform; t != nil {
+		fmt.Fprintf(j.out, "%v: tilt %v mm per column, %v mm per row\n", m.Name, t.Col.Z, t.Row.Z)
+	} else if dz := far.Z - a1.Z; dz != 0 {
+		fmt.Fprintf(j.out, "%v: %v is %v mm higher than A1, only the height of A1 is kept\n", m.Name,
+			pb.WellName(m.Rows-1, m.Columns-1), dz)
+	}
+	if err = pb.SaveMatrix(deckFile, m.Name, fit); err != nil {
+		return err
+	}
+	fmt.Fprintf(j.out, "saved %v to %v\n", m.Name, deckFile)
+	return nil
+}
+
+// measure moves just above the cell at row, col as the deck has it now and
+// returns where the user jogs to from there.
+func (j *jogger) measure(m *pb.Matrix, row, col int) (*pb.Position, error) {
+	c := m.Cells[row][col]
+	if err := j.approach(c.Position); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(j.out, "jog to the center of %v %v and press enter\n", m.Name, pb.WellName(row, col))
+	for {
+		fmt.Fprintf(j.out, "\rX%-7v Y%-7v Z%-7v step %v mm   ", j.bot.Commanded.X, j.bot.Commanded.Y, j.bot.Commanded.Z, pb.JogSteps[j.step])
+		k, err := j.key()
+		if err != nil {
+			return nil, err
+		}
+		s := pb.JogSteps[j.step]
+		var dx, dy, dz float32
+		switch k {
+		case 'a', 'D':
+			dx = -s
+		case 'd', 'C':
+			dx = s
+		case 's', 'B':
+			dy = -s
+		case 'w', 'A':
+			dy = s
+		case 'f':
+			dz = -s
+		case 'r':
+			dz = s
+		case '1', '2', '3':
+			if i := int(k - '1'); i < len(pb.JogSteps) {
+				j.step = i
+			}
+			continue
+		case '\n', '\r':
+			fmt.Fprintln(j.out)
+			p := j.bot.Commanded
+			return &p, nil
+		case 'q', 3:
+			fmt.Fprintln(j.out)
+			return nil, errAborted
+		default:
+			continue
+		}
+		if err = j.bot.Jog(dx, dy, dz); err != nil {
+			if !errors.Is(err, pb.ErrOutOfBounds) {
+				return nil, err
+			}
+			fmt.Fprintf(j.out, "\n%v\n", err)
+		}
+	}
+}
+
+// key reads one key press. Arrow keys come back as the last letter of their
+// escape sequence.
+func (j *jogger) key() (byte, error) {
+	k, err := j.keys.ReadByte()
+	if err != nil {
+		if err == io.EOF {
+			return 0, errAborted
+		}
+		return 0, err
+	}
+	if k != 0x1b {
+		return k, nil
+	}
+	if b, err := j.keys.ReadByte(); err != nil || b != '[' {
+		return 0, err
+	}
+	return j.keys.ReadByte()
+}
+
+// approach lifts clear of the deck, crosses over to p and comes down to just
+// above it.
+func (j *jogger) approach(p pb.Position) error {
+	if err := j.bot.GoTo(j.bot.Commanded.WithZ(pb.TipBoxClear)); err != nil {
+		return err
+	}
+	if err := j.bot.GoTo(p.WithZ(pb.TipBoxClear)); err != nil {
+		return err
+	}
+	return j.bot.GoTo(p.Add(0, 0, 5))
+}
+
+func init() {
+	calibrateCmd.AddCommand(deckCmd)
+	deckCmd.Flags().IntVar(&calCorners, "corners", 2, "corner wells to measure, 3 fits skew and tilt as well")
+}
@@ -6,7 +6,6 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
-	pb "pipbot/pipbot"
 )
 
 // homeCmd represents the home command
@@ -14,10 +13,14 @@ var homeCmd = &cobra.Command{
 	Use:   "home",
 	Short: "homes the bot",
 	Long:  `Sends G28. Be wary of clearances and things hitting other things!!`,
-	Run: func(cmd *cobra.Command, args []string) {
-		bot := pb.NewPipBot(pb.Port, pb.Baud, 0)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bot, err := newBot()
+		if err != nil {
+			return err
+		}
+		defer bot.Close()
 		bot.Rate = 500
-		bot.Home()
+		return bot.Home()
 	},
 }
 
@@ -0,0 +1,167 @@
+/*
+Copyright © 2023 Jonathan Taylor <jonrtaylor12@gmail.com>
+*/
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	pb "pipbot/pipbot"
+)
+
+var (
+	protoOut      string
+	protoVolume   float64
+	protoDilution pb.Dilution
+	protoNorm     pb.Normalization
+	protoDiluent  string
+	protoSample   string
+	protoStart    string
+	protoByColumn bool
+)
+
+// protocolCmd represents the protocol command
+var protocolCmd = &cobra.Command{
+	Use:   "protocol",
+	Short: "writes recipes for common protocols",
+	Long: `Writes a CSV recipe for a common protocol, to --out or the terminal. Check it,
+then run it with "pipbot tip -r".`,
+}
+
+var diluteCmd = &cobra.Command{
+	Use:   "dilute <plate> <start-well>",
+	Short: "serial dilution along a row or column",
+	Long: `Fills the --steps wells after start-well with --volume of diluent, then passes
+volume/(factor-1) down the series, mixing each well after it is filled.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		d := protoDilution
+		d.Labware, d.Start = args[0], args[1]
+		d.Volume = protoVolume
+		var err error
+		if d.DiluentLabware, d.DiluentWell, err = pb.SplitCell(protoDiluent); err != nil {
+			return err
+		}
+		if protoSample != "" {
+			if d.SampleLabware, d.SampleWell, err = pb.SplitCell(protoSample); err != nil {
+				return err
+			}
+		}
+		r, err := pb.SerialDilution(d)
+		if err != nil {
+			return err
+		}
+		return writeRecipe(cmd, r)
+	},
+}
+
+var stampCmd = &cobra.Command{
+	Use:   "stamp <from> <to>",
+	Short: "copies every well of one matrix into another",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		layout, err := loadLayout()
+		if err != nil {
+			return err
+		}
+		r, err := pb.Stamp(layout, args[0], args[1], protoVolume)
+		if err != nil {
+			return err
+		}
+		return writeRecipe(cmd, r)
+	},
+}
+
+var normalizeCmd = &cobra.Command{
+	Use:   "normalize <concentrations.csv>",
+	Short: "dilutes samples to a common concentration",
+	Long: `Reads the concentration of each sample well from a CSV with the columns well and
+concentration, and optionally dest_well, and makes up --volume of each at
+--target in the destination plate.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n := protoNorm
+		n.Volume, n.File = protoVolume, args[0]
+		var err error
+		if n.Samples, err = pb.LoadConcentrations(args[0]); err != nil {
+			return err
+		}
+		if n.DiluentLabware, n.DiluentWell, err = pb.SplitCell(protoDiluent); err != nil {
+			return err
+		}
+		r, err := pb.Normalize(n)
+		if err != nil {
+			return err
+		}
+		return writeRecipe(cmd, r)
+	},
+}
+
+var cherryPickCmd = &cobra.Command{
+	Use:   "cherry-pick <picks.csv> <dest>",
+	Short: "gathers listed wells into a plate",
+	Long: `Reads the wells to pick from a CSV with the columns source_labware and
+source_well, and optionally volume, and puts them into consecutive wells of
+dest from --start.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		layout, err := loadLayout()
+		if err != nil {
+			return err
+		}
+		picks, err := pb.LoadPicks(args[0])
+		if err != nil {
+			return err
+		}
+		r, err := pb.CherryPick(layout, picks, args[1], protoStart, protoByColumn, protoVolume)
+		if err != nil {
+			return err
+		}
+		return writeRecipe(cmd, r)
+	},
+}
+
+// writeRecipe writes r to --out, or the terminal.
+func writeRecipe(cmd *cobra.Command, r *pb.Recipe) error {
+	if protoOut == "" {
+		return r.WriteCSV(cmd.OutOrStdout())
+	}
+	f, err := os.Create(protoOut)
+	if err != nil {
+		return err
+	}
+	if err = r.WriteCSV(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func init() {
+	rootCmd.AddCommand(protocolCmd)
+	protocolCmd.AddCommand(diluteCmd, stampCmd, normalizeCmd, cherryPickCmd)
+	protocolCmd.PersistentFlags().StringVarP(&protoOut, "out", "o", "", "recipe file to write")
+	protocolCmd.PersistentFlags().Float64VarP(&protoVolume, "volume", "v", 100, "volume in µL")
+
+	diluteCmd.Flags().IntVar(&protoDilution.Steps, "steps", 7, "number of wells to dilute into")
+	diluteCmd.Flags().Float64Var(&protoDilution.Factor, "factor", 2, "how many times weaker each well is")
+	diluteCmd.Flags().BoolVar(&protoDilution.Down, "down", false, "run down a column instead of along a row")
+	diluteCmd.Flags().StringVar(&protoDiluent, "diluent", "", "matrix:well to draw diluent from")
+	diluteCmd.Flags().StringVar(&protoSample, "sample", "", "matrix:well to fill the start well from, if it is not filled already")
+	diluteCmd.Flags().IntVar(&protoDilution.MixCycles, "mix", 3, "times to mix each well")
+	diluteCmd.Flags().Float64Var(&protoDilution.MixVolume, "mix-volume", 0, "volume to mix with, in µL; what is passed on if 0")
+	_ = diluteCmd.MarkFlagRequired("diluent")
+
+	normalizeCmd.Flags().StringVar(&protoNorm.SampleLabware, "samples", "", "matrix holding the samples")
+	normalizeCmd.Flags().StringVar(&protoNorm.DestLabware, "dest", "", "matrix to make the normalized samples in")
+	normalizeCmd.Flags().StringVar(&protoDiluent, "diluent", "", "matrix:well to draw diluent from")
+	normalizeCmd.Flags().Float64Var(&protoNorm.Target, "target", 0, "concentration to bring every sample to")
+	for _, f := range []string{"samples", "dest", "diluent", "target"} {
+		_ = normalizeCmd.MarkFlagRequired(f)
+	}
+
+	cherryPickCmd.Flags().StringVar(&protoStart, "start", "A1", "first destination well")
+	cherryPickCmd.Flags().BoolVar(&protoByColumn, "by-column", false, "fill down columns instead of along rows")
+}
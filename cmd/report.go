@@ -0,0 +1,87 @@
+/*
+Copyright © 2023 Jonathan Taylor <jonrtaylor12@gmail.com>
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	pb "pipbot/pipbot"
+)
+
+var (
+	reportFormat string
+	reportOut    string
+)
+
+// reportCmd represents the report command
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "reports what a recipe leaves in each well",
+	Long: `Works out the final volume of every destination well of a recipe or plate map
+and the concentration of each stock in it, following dilutions back to the stocks
+they were made from. Stocks need their concentration in the deck file for that,
+as in concentrations: {A1: 10 mM}; the others are reported as a percentage by
+volume. The report is a CSV or JSON file for the lab notebook, or a plate map
+table per destination matrix.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		layout, err := loadLayout()
+		if err != nil {
+			return err
+		}
+		r, err := pb.OpenRecipe(recipeFile, legendFile)
+		if err != nil {
+			return err
+		}
+		rep, err := layout.Report(r)
+		if err != nil {
+			return err
+		}
+		return writeReport(cmd.OutOrStdout(), rep, reportOut, reportFormat)
+	},
+}
+
+// writeReport writes rep to file, or to w if file is empty, as format. An
+// empty format is taken from the file's extension, or is a table.
+func writeReport(w io.Writer, rep *pb.Report, file, format string) error {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(file)), ".")
+	}
+	write := rep.WriteTable
+	switch format {
+	case "csv":
+		write = rep.WriteCSV
+	case "json":
+		write = rep.WriteJSON
+	case "", "table", "txt":
+	default:
+		return fmt.Errorf("unknown report format %q, expected csv, json or table", format)
+	}
+	if file == "" {
+		return write(w)
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err = write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.Flags().StringVarP(&recipeFile, "recipe", "r", "recipe.csv", "recipe or plate map to report on")
+	reportCmd.Flags().StringVarP(&legendFile, "legend", "l", "", "legend for the labels of a plate map")
+	reportCmd.Flags().StringVarP(&reportFormat, "format", "f", "", "csv, json or table; taken from --out if not given")
+	reportCmd.Flags().StringVarP(&reportOut, "out", "o", "", "file to write the report to instead of the terminal")
+}
@@ -5,11 +5,85 @@ Copyright © 2023 Jonathan Taylor <jonrtaylor12@gmail.com>
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
+	pb "pipbot/pipbot"
 )
 
+var (
+	port     string
+	baud     int
+	deckFile string
+	tipsFile string
+)
+
+// newBot connects to the printer with the deck from --deck, or the built-in
+// one, and the tip inventory from --tips. --port and --baud win over the deck
+// file when given.
+func newBot() (*pb.PipBot, error) {
+	return openBot(pb.NewPipBot)
+}
+
+// openBot is newBot with connect making the bot for the port and baud.
+func openBot(connect func(port string, baud int) (*pb.PipBot, error)) (*pb.PipBot, error) {
+	layout := pb.MakeGrid()
+	pipette := pb.StockPipette()
+	p, b := port, baud
+	if deckFile != "" {
+		d, err := pb.LoadDeck(deckFile)
+		if err != nil {
+			return nil, err
+		}
+		if layout, err = d.Layout(); err != nil {
+			return nil, fmt.Errorf("%v: %w", deckFile, err)
+		}
+		if pipette, err = d.Pipette(""); err != nil {
+			return nil, fmt.Errorf("%v: %w", deckFile, err)
+		}
+		if d.Port != "" && !rootCmd.PersistentFlags().Changed("port") {
+			p = d.Port
+		}
+		if d.Baud != 0 && !rootCmd.PersistentFlags().Changed("baud") {
+			b = d.Baud
+		}
+	}
+	tips, err := pb.LoadTips(tipsFile)
+	if err != nil {
+		return nil, err
+	}
+	bot, err := connect(p, b)
+	if err != nil {
+		if filepath.Ext(p) != ".gcode" {
+			return nil, fmt.Errorf("%w: set --port to the printer's serial port, or to a .gcode file to write to", err)
+		}
+		return nil, err
+	}
+	bot.Layout = layout
+	bot.Pipette = pipette
+	bot.Tips = tips
+	return bot, nil
+}
+
+// loadLayout reads the deck from --deck, or the built-in one, without
+// connecting to the printer.
+func loadLayout() (*pb.Layout, error) {
+	if deckFile == "" {
+		return pb.MakeGrid(), nil
+	}
+	d, err := pb.LoadDeck(deckFile)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := d.Layout()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", deckFile, err)
+	}
+	return layout, nil
+}
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "pipbot",
@@ -20,6 +94,7 @@ examples and usage of using your application. For example:
 Cobra is a CLI library for Go that empowers applications.
 This application is a tool to generate the needed files
 to quickly create a Cobra application.`,
+	SilenceUsage: true,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
@@ -40,6 +115,10 @@ func init() {
 	// will be global for your application.
 
 	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.pipbot.yaml)")
+	rootCmd.PersistentFlags().StringVarP(&port, "port", "p", pb.Port, "serial port of the printer, or a .gcode file to write to")
+	rootCmd.PersistentFlags().IntVarP(&baud, "baud", "b", pb.Baud, "baud rate of the serial port")
+	rootCmd.PersistentFlags().StringVarP(&deckFile, "deck", "d", "", "deck layout file (YAML or JSON)")
+	rootCmd.PersistentFlags().StringVar(&tipsFile, "tips", "tips.yaml", "file keeping track of the tips used from each rack")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"pipbot/graph"
+	pb "pipbot/pipbot"
 
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/playground"
@@ -28,7 +29,7 @@ func runServer() {
 		port = defaultPort
 	}
 
-	srv := handler.NewDefaultServer(graph.NewExecutableSchema(graph.Config{Resolvers: &graph.Resolver{}}))
+	srv := handler.NewDefaultServer(graph.NewExecutableSchema(graph.Config{Resolvers: &graph.Resolver{Bot: planningBot}}))
 
 	http.Handle("/", playground.Handler("GraphQL playground", "/query"))
 	http.Handle("/query", srv)
@@ -37,6 +38,14 @@ func runServer() {
 	log.Fatal(http.ListenAndServe(":"+port, nil))
 }
 
+// planningBot is a bot on a Simulator with the deck from --deck and the tips
+// from --tips, for estimating recipes on their own grids with its tip racks.
+func planningBot() (*pb.PipBot, error) {
+	return openBot(func(string, int) (*pb.PipBot, error) {
+		return pb.NewPipBotOn(pb.NewSimulator(nil)), nil
+	})
+}
+
 func init() {
 	rootCmd.AddCommand(serveCmd)
 }
@@ -0,0 +1,95 @@
+/*
+Copyright © 2023 Jonathan Taylor <jonrtaylor12@gmail.com>
+*/
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	pb "pipbot/pipbot"
+)
+
+var simTimeline bool
+
+// simulateCmd represents the simulate command
+var simulateCmd = &cobra.Command{
+	Use:   "simulate [file.gcode]",
+	Short: "dry runs a recipe, or a G-code file, on a virtual printer",
+	Long: `Plans the recipe the way tip does and runs it on a virtual printer instead of the real
+one, or runs a G-code file written earlier. It prints how long the run would take, how far
+the gantry travels, how far the plunger draws and dispenses and anything the printer would
+get wrong, such as moves outside the build volume or the plunger pushed past the bottom.
+The tip inventory is not changed.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var res *pb.Simulation
+		if len(args) == 1 {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			pipette := pb.StockPipette()
+			if deckFile != "" {
+				d, err := pb.LoadDeck(deckFile)
+				if err != nil {
+					return err
+				}
+				if pipette, err = d.Pipette(""); err != nil {
+					return err
+				}
+			}
+			if res, err = pb.Simulate(f, pipette); err != nil {
+				return err
+			}
+		} else {
+			sim := pb.NewSimulator(nil)
+			bot, err := openBot(func(string, int) (*pb.PipBot, error) {
+				return pb.NewPipBotOn(sim), nil
+			})
+			if err != nil {
+				return err
+			}
+			sim.Pipette = bot.Pipette
+			bot.Tips = bot.Tips.Copy()
+			bot.Rate = 500
+			bot.TipPolicy = pb.TipPolicy(tipPolicy)
+			if err = bot.Init(); err != nil {
+				return err
+			}
+			r, err := pb.OpenRecipe(recipeFile, legendFile)
+			if err != nil {
+				return err
+			}
+			actions, err := bot.PlanRecipe(r)
+			if err != nil {
+				return err
+			}
+			if err = bot.Run(actions); err != nil {
+				return err
+			}
+			if err = bot.Close(); err != nil {
+				return err
+			}
+			res = sim.Result()
+		}
+		out := cmd.OutOrStdout()
+		if simTimeline {
+			if err := res.WriteTimeline(out); err != nil {
+				return err
+			}
+		}
+		return res.WriteSummary(out)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(simulateCmd)
+	simulateCmd.Flags().StringVarP(&recipeFile, "recipe", "r", "recipe.csv", "recipe or plate map to run")
+	simulateCmd.Flags().StringVarP(&legendFile, "legend", "l", "", "legend for the labels of a plate map")
+	simulateCmd.Flags().StringVar(&tipPolicy, "tip-policy", string(pb.DefaultTipPolicy),
+		"when to change tips: always, never, per-source, per-destination or per-group")
+	simulateCmd.Flags().BoolVar(&simTimeline, "timeline", false, "print every step with when it starts and where the gantry ends up")
+}
@@ -4,30 +4,110 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"bufio"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
 	"github.com/spf13/cobra"
 	pb "pipbot/pipbot"
 )
 
+var (
+	recipeFile, legendFile string
+	tipPolicy              string
+	promptTips             bool
+	tipReport              string
+	confirmRun             bool
+)
+
+// errCalledOff is returned when the user does not confirm a run.
+var errCalledOff = errors.New("run called off")
+
 // tipCmd represents the tip command
 var tipCmd = &cobra.Command{
 	Use:   "tip",
 	Short: "use to get tip",
 	Long:  `tip gets tips `,
-	Run: func(cmd *cobra.Command, args []string) {
-		bot := pb.NewPipBot(pb.Port, pb.Baud, 0)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bot, err := newBot()
+		if err != nil {
+			return err
+		}
+		defer bot.Close()
 		bot.Rate = 500
+		bot.TipPolicy = pb.TipPolicy(tipPolicy)
+		in := bufio.NewReader(cmd.InOrStdin())
+		if promptTips {
+			bot.Prompt = func(msg string) error {
+				fmt.Fprintf(cmd.OutOrStdout(), "%v, then press Enter: ", msg)
+				_, err := in.ReadString('\n')
+				return err
+			}
+		}
 		ctx := context.Background()
 		_ = bot.Listen(ctx)
-		bot.Init()
 		//bp := bot.Layout.Matrices[2]
 		//wp := bot.Layout.Matrices[1]
-		bot.Plan("recipe.csv")
-		bot.Run()
+		r, err := pb.OpenRecipe(recipeFile, legendFile)
+		if err != nil {
+			return err
+		}
+		// plan and estimate before anything moves, so the run can be called off
+		if err = bot.Prepare(); err != nil {
+			return err
+		}
+		actions, err := bot.PlanRecipe(r)
+		if err != nil {
+			return err
+		}
+		est, err := bot.Estimate(actions)
+		if err != nil {
+			return err
+		}
+		if err = est.WriteSummary(cmd.OutOrStdout()); err != nil {
+			return err
+		}
+		if confirmRun {
+			fmt.Fprint(cmd.OutOrStdout(), "run it? [y/N] ")
+			answer, err := in.ReadString('\n')
+			if err != nil && err != io.EOF {
+				return err
+			}
+			if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+				return errCalledOff
+			}
+		}
+		if err = bot.Init(); err != nil {
+			return err
+		}
+		if err = bot.Run(actions); err != nil {
+			s := bot.State()
+			cmd.PrintErrf("stopped at step %v while %v with tip loaded: %v\n", s.Step, s.Phase, s.HasTip)
+			cmd.PrintErrf("sent to X%v Y%v Z%v\n", s.Commanded.X, s.Commanded.Y, s.Commanded.Z)
+			if p, err := bot.Locate(); err == nil {
+				cmd.PrintErrf("the printer says it is at X%v Y%v Z%v\n", p.X, p.Y, p.Z)
+			}
+			return err
+		}
+		if tipReport != "" {
+			return writeReport(cmd.OutOrStdout(), bot.Layout.RunReport(actions), tipReport, "")
+		}
+		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(tipCmd)
+	tipCmd.Flags().StringVarP(&recipeFile, "recipe", "r", "recipe.csv", "recipe or plate map to run")
+	tipCmd.Flags().StringVarP(&legendFile, "legend", "l", "", "legend for the labels of a plate map")
+	tipCmd.Flags().StringVar(&tipPolicy, "tip-policy", string(pb.DefaultTipPolicy),
+		"when to change tips: always, never, per-source, per-destination or per-group")
+	tipCmd.Flags().BoolVar(&promptTips, "prompt", false,
+		"ask here for empty tip racks to be replaced instead of waiting for the printer's button")
+	tipCmd.Flags().BoolVar(&confirmRun, "confirm", false, "ask before starting the run, once the estimate is printed")
+	tipCmd.Flags().StringVar(&tipReport, "report", "", "file to write what ended up in each well to after the run (.csv, .json or .txt)")
 
 }
@@ -0,0 +1,116 @@
+/*
+Copyright © 2023 Jonathan Taylor <jonrtaylor12@gmail.com>
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	pb "pipbot/pipbot"
+)
+
+// tipsCmd shows how many tips are left in each rack
+var tipsCmd = &cobra.Command{
+	Use:   "tips",
+	Short: "shows the tips left in each rack",
+	Long: `Shows how many tips are left in each tip rack on the deck, as kept in the
+--tips file. Runs take tips from where the last one left off.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		layout, tips, err := loadTips()
+		if err != nil {
+			return err
+		}
+		for _, m := range layout.Matrices {
+			if m.Kind != pb.Tip {
+				continue
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%v\t%v\t%v/%v left\n", m.Name, m.Pipette, tips.Left(m), m.Rows*m.Columns)
+		}
+		return nil
+	},
+}
+
+// tipsMarkCmd records a part used rack
+var tipsMarkCmd = &cobra.Command{
+	Use:   "mark <rack> <count|well>",
+	Short: "records the tips already taken from a rack",
+	Long: `Records that a rack was put on the deck part used. Give either the number of
+tips taken, or the well of the first tip still there; tips are taken along each
+row in turn.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		layout, tips, err := loadTips()
+		if err != nil {
+			return err
+		}
+		m, err := layout.Matrix(args[0])
+		if err != nil {
+			return err
+		}
+		if m.Kind != pb.Tip {
+			return fmt.Errorf("%v is not a tip rack", m.Name)
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			row, col, werr := pb.ParseWell(args[1])
+			if werr != nil {
+				return fmt.Errorf("expected a count or a well, got %q", args[1])
+			}
+			n = row*m.Columns + col
+		}
+		if err = tips.Mark(m, n); err != nil {
+			return err
+		}
+		return tips.Save()
+	},
+}
+
+// tipsResetCmd marks racks as full
+var tipsResetCmd = &cobra.Command{
+	Use:   "reset [rack...]",
+	Short: "marks tip racks as full",
+	Long:  `Marks the named tip racks, or all of them, as full after they have been replaced.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		layout, tips, err := loadTips()
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			for _, m := range layout.Matrices {
+				if m.Kind == pb.Tip {
+					args = append(args, m.Name)
+				}
+			}
+		}
+		for _, name := range args {
+			if _, err = layout.Matrix(name); err != nil {
+				return err
+			}
+		}
+		tips.Reset(args...)
+		return tips.Save()
+	},
+}
+
+// loadTips reads the deck from --deck, or the built-in one, and the
+// inventory from --tips without connecting to the printer.
+func loadTips() (*pb.Layout, *pb.TipInventory, error) {
+	layout, err := loadLayout()
+	if err != nil {
+		return nil, nil, err
+	}
+	tips, err := pb.LoadTips(tipsFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	return layout, tips, nil
+}
+
+func init() {
+	rootCmd.AddCommand(tipsCmd)
+	tipsCmd.AddCommand(tipsMarkCmd, tipsResetCmd)
+}
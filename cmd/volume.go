@@ -0,0 +1,208 @@
+/*
+Copyright © 2023 Jonathan Taylor <jonrtaylor12@gmail.com>
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	pb "pipbot/pipbot"
+)
+
+var (
+	calVolumes   []float64
+	calReps      int
+	calSource    string
+	calDest      string
+	calMasses    string
+	calDensity   float64
+	calPiecewise bool
+	calNoRun     bool
+)
+
+// volumeCmd represents the calibrate volume command
+var volumeCmd = &cobra.Command{
+	Use:   "volume",
+	Short: "fits the pipette volume curve from gravimetric data",
+	Long: `Dispenses each of --volumes --reps times from --source onto a balance at --dest. After
+each dispense it waits for the mass in mg to be typed in, so the balance can be read and
+tared before the next one. With --masses the cycles run back to back and the masses are
+read from the file instead, which has one row per cycle with the nominal volume and the
+mass: volume,mass.
+
+The fitted curve is written to the pipette in the deck file, and the CV and accuracy at
+each volume are reported so the instrument can be qualified.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bot, err := newBot()
+		if err != nil {
+			return err
+		}
+		defer bot.Close()
+
+		var ms []pb.Measurement
+		if !calNoRun {
+			if ms, err = runCycles(cmd, bot); err != nil {
+				return err
+			}
+		}
+		if ms, err = readMasses(bot.Pipette, ms); err != nil {
+			return err
+		}
+		cal, err := pb.FitCurve(ms, float32(calDensity), calPiecewise)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "%10v %4v %10v %8v %7v %10v\n", "nominal", "n", "mean", "sd", "cv%", "accuracy%")
+		for _, s := range cal.Stats {
+			fmt.Fprintf(out, "%10.2f %4d %10.2f %8.3f %7.2f %10.2f\n", s.Nominal, s.N, s.Mean, s.SD, s.CV, s.Accuracy)
+		}
+		fmt.Fprintf(out, "slope %v mm/µL, intercept %v mm\n", cal.Curve.Slope, cal.Curve.Intercept)
+
+		bot.Pipette.Curve = cal.Curve
+		if deckFile == "" {
+			fmt.Fprintln(out, "no --deck given, curve not saved")
+			return nil
+		}
+		if err = pb.SavePipette(deckFile, bot.Pipette); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "saved to pipette %q in %v\n", bot.Pipette.Name, deckFile)
+		return nil
+	},
+}
+
+// runCycles dispenses every volume calReps times, with a fresh tip per volume.
+// Without --masses it asks for the mass of each dispense as soon as it has
+// landed.
+func runCycles(cmd *cobra.Command, bot *pb.PipBot) ([]pb.Measurement, error) {
+	src, err := bot.Layout.Cell(calSource)
+	if err != nil {
+		return nil, err
+	}
+	dest, err := bot.Layout.Cell(calDest)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range calVolumes {
+		if err = bot.Pipette.Check(float32(v)); err != nil {
+			return nil, err
+		}
+	}
+	if err = bot.Init(); err != nil {
+		return nil, err
+	}
+	var in *bufio.Scanner
+	if calMasses == "" {
+		in = bufio.NewScanner(cmd.InOrStdin())
+	}
+	var ms []pb.Measurement
+	for _, v := range calVolumes {
+		for r := 0; r < calReps; r++ {
+			cmd.Printf("dispensing %v µL, %v/%v\n", v, r+1, calReps)
+			if err = bot.Transfer(src, dest, float32(v), r == calReps-1); err != nil {
+				return nil, err
+			}
+			m := pb.Measurement{Nominal: float32(v), Travel: bot.Pipette.Curve.Travel(float32(v))}
+			if in != nil {
+				if err = bot.Wait(); err != nil {
+					return nil, err
+				}
+				if m.Mass, err = askMass(cmd, in, len(ms)+1, m.Nominal); err != nil {
+					return nil, err
+				}
+			}
+			ms = append(ms, m)
+		}
+	}
+	return ms, nil
+}
+
+// askMass reads the mass of cycle i from in.
+func askMass(cmd *cobra.Command, in *bufio.Scanner, i int, nominal float32) (float32, error) {
+	cmd.Printf("mass in mg of cycle %v (%v µL), then tare the balance: ", i, nominal)
+	if !in.Scan() {
+		if err := in.Err(); err != nil {
+			return 0, err
+		}
+		return 0, io.ErrUnexpectedEOF
+	}
+	m, err := strconv.ParseFloat(strings.TrimSpace(in.Text()), 32)
+	if err != nil {
+		return 0, fmt.Errorf("cycle %v: %w", i, err)
+	}
+	return float32(m), nil
+}
+
+// readMasses fills in the mass of each cycle in ms from --masses. Without
+// cycles every row of --masses becomes one. Masses typed in as the cycles ran
+// are already in ms.
+func readMasses(p *pb.Pipette, ms []pb.Measurement) ([]pb.Measurement, error) {
+	if calMasses == "" {
+		if len(ms) == 0 {
+			return nil, fmt.Errorf("--no-run needs --masses")
+		}
+		return ms, nil
+	}
+
+	f, err := os.Open(calMasses)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > 0 {
+		if _, err = strconv.ParseFloat(rows[0][0], 32); err != nil {
+			rows = rows[1:] // header
+		}
+	}
+	if len(ms) != 0 && len(rows) != len(ms) {
+		return nil, fmt.Errorf("%v: %v masses for %v cycles", calMasses, len(rows), len(ms))
+	}
+	for i, row := range rows {
+		if len(row) < 2 {
+			return nil, fmt.Errorf("%v:%v: expected volume,mass", calMasses, i+1)
+		}
+		v, err := strconv.ParseFloat(row[0], 32)
+		if err != nil {
+			return nil, fmt.Errorf("%v:%v: %w", calMasses, i+1, err)
+		}
+		m, err := strconv.ParseFloat(row[1], 32)
+		if err != nil {
+			return nil, fmt.Errorf("%v:%v: %w", calMasses, i+1, err)
+		}
+		if len(ms) == len(rows) {
+			if ms[i].Nominal != float32(v) {
+				return nil, fmt.Errorf("%v:%v: volume %v does not match cycle volume %v", calMasses, i+1, v, ms[i].Nominal)
+			}
+			ms[i].Mass = float32(m)
+			continue
+		}
+		ms = append(ms, pb.Measurement{Nominal: float32(v), Travel: p.Curve.Travel(float32(v)), Mass: float32(m)})
+	}
+	return ms, nil
+}
+
+func init() {
+	calibrateCmd.AddCommand(volumeCmd)
+	volumeCmd.Flags().Float64SliceVar(&calVolumes, "volumes", []float64{20, 50, 100, 200}, "volumes to dispense, µL")
+	volumeCmd.Flags().IntVar(&calReps, "reps", 5, "dispenses per volume")
+	volumeCmd.Flags().StringVar(&calSource, "source", "12:A1", "cell to draw water from, matrix:well")
+	volumeCmd.Flags().StringVar(&calDest, "dest", "96:A1", "cell on the balance, matrix:well")
+	volumeCmd.Flags().StringVar(&calMasses, "masses", "", "CSV of volume,mass (mg) per cycle instead of typing them in")
+	volumeCmd.Flags().Float64Var(&calDensity, "density", float64(pb.WaterDensity), "density of the liquid, mg/µL")
+	volumeCmd.Flags().BoolVar(&calPiecewise, "piecewise", false, "fit a piecewise curve through each volume instead of a line")
+	volumeCmd.Flags().BoolVar(&calNoRun, "no-run", false, "only fit the masses in --masses, do not move")
+}
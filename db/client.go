@@ -2,7 +2,9 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"pipbot/graph"
 	"pipbot/graph/model"
 )
@@ -110,9 +112,40 @@ func ConvertTransfer(t *TransferModel) (*model.Transfer, error) {
 	if group, exists := t.Group(); exists {
 		ret.Group = &group
 	}
+	if policy, exists := t.TipPolicy(); exists {
+		ret.TipPolicy = &policy
+	}
+	if class, exists := t.LiquidClass(); exists {
+		ret.LiquidClass = &class
+	}
+	for _, m := range []struct {
+		dst  **model.Mix
+		get  func() (string, bool)
+		name string
+	}{{&ret.MixBefore, t.MixBefore, "mixBefore"}, {&ret.MixAfter, t.MixAfter, "mixAfter"}} {
+		if s, exists := m.get(); exists && s != "" {
+			*m.dst = &model.Mix{}
+			if err := json.Unmarshal([]byte(s), *m.dst); err != nil {
+				return nil, fmt.Errorf("transfer %v: %v: %w", t.ID, m.name, err)
+			}
+		}
+	}
 	return ret, nil
 }
 
+// encodeMix stores m as JSON, or nothing if it is nil.
+func encodeMix(m *model.NewMix) (*string, error) {
+	if m == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(&model.Mix{Cycles: m.Cycles, Volume: m.Volume, Height: m.Height, Rate: m.Rate})
+	if err != nil {
+		return nil, err
+	}
+	s := string(b)
+	return &s, nil
+}
+
 func ConvertRecipe(r *RecipeModel) (*model.Recipe, error) {
 	mat, err := ConvertMatrix(r.Matrix())
 	if err != nil {
@@ -281,6 +314,14 @@ func (c *Client) AddTransfer(ctx context.Context, recipeID string, transfer mode
 	if transfer.Dest.Aspirate != nil {
 		aspirateDest = *transfer.Dest.Aspirate
 	}
+	mixBefore, err := encodeMix(transfer.MixBefore)
+	if err != nil {
+		return nil, err
+	}
+	mixAfter, err := encodeMix(transfer.MixAfter)
+	if err != nil {
+		return nil, err
+	}
 	t, err := c.Transfer.CreateOne(
 		Transfer.SampleID.Set(transfer.SampleID),
 		Transfer.SourceGrid.Set(transfer.Source.Grid),
@@ -293,6 +334,10 @@ func (c *Client) AddTransfer(ctx context.Context, recipeID string, transfer mode
 		Transfer.Recipe.Link(
 			Recipe.ID.Equals(recipeID),
 		),
+		Transfer.TipPolicy.SetIfPresent(transfer.TipPolicy),
+		Transfer.LiquidClass.SetIfPresent(transfer.LiquidClass),
+		Transfer.MixBefore.SetIfPresent(mixBefore),
+		Transfer.MixAfter.SetIfPresent(mixAfter),
 	).Exec(ctx)
 	if err != nil {
 		return nil, err
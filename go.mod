@@ -11,7 +11,9 @@ require (
 	github.com/steebchen/prisma-client-go v0.25.0
 	github.com/takuoki/gocase v1.0.0
 	github.com/vektah/gqlparser/v2 v2.5.10
+	golang.org/x/sys v0.8.0
 	golang.org/x/text v0.13.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -27,7 +29,5 @@ require (
 	github.com/urfave/cli/v2 v2.25.5 // indirect
 	github.com/xrash/smetrics v0.0.0-20201216005158-039620a65673 // indirect
 	golang.org/x/mod v0.10.0 // indirect
-	golang.org/x/sys v0.8.0 // indirect
 	golang.org/x/tools v0.9.3 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -39,12 +39,22 @@ type Config struct {
 type ResolverRoot interface {
 	Mutation() MutationResolver
 	Query() QueryResolver
+	Recipe() RecipeResolver
 }
 
 type DirectiveRoot struct {
 }
 
 type ComplexityRoot struct {
+	Estimate struct {
+		RackSwaps     func(childComplexity int) int
+		Seconds       func(childComplexity int) int
+		Sources       func(childComplexity int) int
+		TipsAvailable func(childComplexity int) int
+		TipsNeeded    func(childComplexity int) int
+		Transfers     func(childComplexity int) int
+	}
+
 	Grid struct {
 		ColSpace func(childComplexity int) int
 		Home     func(childComplexity int) int
@@ -61,6 +71,13 @@ type ComplexityRoot struct {
 		Name  func(childComplexity int) int
 	}
 
+	Mix struct {
+		Cycles func(childComplexity int) int
+		Height func(childComplexity int) int
+		Rate   func(childComplexity int) int
+		Volume func(childComplexity int) int
+	}
+
 	Mutation struct {
 		AddGrid      func(childComplexity int, matrixID string, grid model.NewGrid) int
 		AddTransfer  func(childComplexity int, recipeID string, transfer model.NewTransfer) int
@@ -89,20 +106,30 @@ type ComplexityRoot struct {
 
 	Recipe struct {
 		Download  func(childComplexity int) int
+		Estimate  func(childComplexity int) int
 		ID        func(childComplexity int) int
 		Matrix    func(childComplexity int) int
 		Name      func(childComplexity int) int
 		Transfers func(childComplexity int) int
 	}
 
+	SourceVolume struct {
+		Source func(childComplexity int) int
+		Volume func(childComplexity int) int
+	}
+
 	Transfer struct {
-		Dest     func(childComplexity int) int
-		Group    func(childComplexity int) int
-		ID       func(childComplexity int) int
-		Name     func(childComplexity int) int
-		SampleID func(childComplexity int) int
-		Source   func(childComplexity int) int
-		Volume   func(childComplexity int) int
+		Dest        func(childComplexity int) int
+		Group       func(childComplexity int) int
+		ID          func(childComplexity int) int
+		LiquidClass func(childComplexity int) int
+		MixAfter    func(childComplexity int) int
+		MixBefore   func(childComplexity int) int
+		Name        func(childComplexity int) int
+		SampleID    func(childComplexity int) int
+		Source      func(childComplexity int) int
+		TipPolicy   func(childComplexity int) int
+		Volume      func(childComplexity int) int
 	}
 }
 
@@ -118,6 +145,9 @@ type QueryResolver interface {
 	Recipes(ctx context.Context) ([]*model.Recipe, error)
 	Recipe(ctx context.Context, id string) (*model.Recipe, error)
 }
+type RecipeResolver interface {
+	Estimate(ctx context.Context, obj *model.Recipe) (*model.Estimate, error)
+}
 
 type executableSchema struct {
 	resolvers  ResolverRoot
@@ -134,6 +164,48 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 	_ = ec
 	switch typeName + "." + field {
 
+	case "Estimate.rackSwaps":
+		if e.complexity.Estimate.RackSwaps == nil {
+			break
+		}
+
+		return e.complexity.Estimate.RackSwaps(childComplexity), true
+
+	case "Estimate.seconds":
+		if e.complexity.Estimate.Seconds == nil {
+			break
+		}
+
+		return e.complexity.Estimate.Seconds(childComplexity), true
+
+	case "Estimate.sources":
+		if e.complexity.Estimate.Sources == nil {
+			break
+		}
+
+		return e.complexity.Estimate.Sources(childComplexity), true
+
+	case "Estimate.tipsAvailable":
+		if e.complexity.Estimate.TipsAvailable == nil {
+			break
+		}
+
+		return e.complexity.Estimate.TipsAvailable(childComplexity), true
+
+	case "Estimate.tipsNeeded":
+		if e.complexity.Estimate.TipsNeeded == nil {
+			break
+		}
+
+		return e.complexity.Estimate.TipsNeeded(childComplexity), true
+
+	case "Estimate.transfers":
+		if e.complexity.Estimate.Transfers == nil {
+			break
+		}
+
+		return e.complexity.Estimate.Transfers(childComplexity), true
+
 	case "Grid.col_space":
 		if e.complexity.Grid.ColSpace == nil {
 			break
@@ -204,6 +276,34 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.Matrix.Name(childComplexity), true
 
+	case "Mix.cycles":
+		if e.complexity.Mix.Cycles == nil {
+			break
+		}
+
+		return e.complexity.Mix.Cycles(childComplexity), true
+
+	case "Mix.height":
+		if e.complexity.Mix.Height == nil {
+			break
+		}
+
+		return e.complexity.Mix.Height(childComplexity), true
+
+	case "Mix.rate":
+		if e.complexity.Mix.Rate == nil {
+			break
+		}
+
+		return e.complexity.Mix.Rate(childComplexity), true
+
+	case "Mix.volume":
+		if e.complexity.Mix.Volume == nil {
+			break
+		}
+
+		return e.complexity.Mix.Volume(childComplexity), true
+
 	case "Mutation.addGrid":
 		if e.complexity.Mutation.AddGrid == nil {
 			break
@@ -339,6 +439,13 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.Recipe.Download(childComplexity), true
 
+	case "Recipe.estimate":
+		if e.complexity.Recipe.Estimate == nil {
+			break
+		}
+
+		return e.complexity.Recipe.Estimate(childComplexity), true
+
 	case "Recipe.id":
 		if e.complexity.Recipe.ID == nil {
 			break
@@ -367,6 +474,20 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.Recipe.Transfers(childComplexity), true
 
+	case "SourceVolume.source":
+		if e.complexity.SourceVolume.Source == nil {
+			break
+		}
+
+		return e.complexity.SourceVolume.Source(childComplexity), true
+
+	case "SourceVolume.volume":
+		if e.complexity.SourceVolume.Volume == nil {
+			break
+		}
+
+		return e.complexity.SourceVolume.Volume(childComplexity), true
+
 	case "Transfer.dest":
 		if e.complexity.Transfer.Dest == nil {
 			break
@@ -388,6 +509,27 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.Transfer.ID(childComplexity), true
 
+	case "Transfer.liquidClass":
+		if e.complexity.Transfer.LiquidClass == nil {
+			break
+		}
+
+		return e.complexity.Transfer.LiquidClass(childComplexity), true
+
+	case "Transfer.mixAfter":
+		if e.complexity.Transfer.MixAfter == nil {
+			break
+		}
+
+		return e.complexity.Transfer.MixAfter(childComplexity), true
+
+	case "Transfer.mixBefore":
+		if e.complexity.Transfer.MixBefore == nil {
+			break
+		}
+
+		return e.complexity.Transfer.MixBefore(childComplexity), true
+
 	case "Transfer.name":
 		if e.complexity.Transfer.Name == nil {
 			break
@@ -409,6 +551,13 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.Transfer.Source(childComplexity), true
 
+	case "Transfer.tipPolicy":
+		if e.complexity.Transfer.TipPolicy == nil {
+			break
+		}
+
+		return e.complexity.Transfer.TipPolicy(childComplexity), true
+
 	case "Transfer.volume":
 		if e.complexity.Transfer.Volume == nil {
 			break
@@ -426,6 +575,7 @@ func (e *executableSchema) Exec(ctx context.Context) graphql.ResponseHandler {
 	inputUnmarshalMap := graphql.BuildUnmarshalerMap(
 		ec.unmarshalInputNewGrid,
 		ec.unmarshalInputNewMatrix,
+		ec.unmarshalInputNewMix,
 		ec.unmarshalInputNewNode,
 		ec.unmarshalInputNewPosition,
 		ec.unmarshalInputNewRecipe,
@@ -707,6 +857,276 @@ func (ec *executionContext) field___Type_fields_args(ctx context.Context, rawArg
 
 // region    **************************** field.gotpl *****************************
 
+func (ec *executionContext) _Estimate_transfers(ctx context.Context, field graphql.CollectedField, obj *model.Estimate) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Estimate_transfers(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.Transfers, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(int)
+	fc.Result = res
+	return ec.marshalNInt2int(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_Estimate_transfers(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Estimate",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type Int does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _Estimate_tipsNeeded(ctx context.Context, field graphql.CollectedField, obj *model.Estimate) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Estimate_tipsNeeded(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.TipsNeeded, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(int)
+	fc.Result = res
+	return ec.marshalNInt2int(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_Estimate_tipsNeeded(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Estimate",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type Int does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _Estimate_tipsAvailable(ctx context.Context, field graphql.CollectedField, obj *model.Estimate) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Estimate_tipsAvailable(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.TipsAvailable, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(int)
+	fc.Result = res
+	return ec.marshalNInt2int(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_Estimate_tipsAvailable(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Estimate",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type Int does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _Estimate_rackSwaps(ctx context.Context, field graphql.CollectedField, obj *model.Estimate) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Estimate_rackSwaps(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.RackSwaps, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(int)
+	fc.Result = res
+	return ec.marshalNInt2int(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_Estimate_rackSwaps(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Estimate",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type Int does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _Estimate_sources(ctx context.Context, field graphql.CollectedField, obj *model.Estimate) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Estimate_sources(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.Sources, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.([]*model.SourceVolume)
+	fc.Result = res
+	return ec.marshalNSourceVolume2ᚕᚖpipbotᚋgraphᚋmodelᚐSourceVolumeᚄ(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_Estimate_sources(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Estimate",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			switch field.Name {
+			case "source":
+				return ec.fieldContext_SourceVolume_source(ctx, field)
+			case "volume":
+				return ec.fieldContext_SourceVolume_volume(ctx, field)
+			}
+			return nil, fmt.Errorf("no field named %q was found under type SourceVolume", field.Name)
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _Estimate_seconds(ctx context.Context, field graphql.CollectedField, obj *model.Estimate) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Estimate_seconds(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.Seconds, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(float64)
+	fc.Result = res
+	return ec.marshalNFloat2float64(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_Estimate_seconds(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Estimate",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type Float does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
 func (ec *executionContext) _Grid_id(ctx context.Context, field graphql.CollectedField, obj *model.Grid) (ret graphql.Marshaler) {
 	fc, err := ec.fieldContext_Grid_id(ctx, field)
 	if err != nil {
@@ -905,7 +1325,183 @@ func (ec *executionContext) _Grid_col_space(ctx context.Context, field graphql.C
 	}()
 	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
 		ctx = rctx // use context from middleware stack in children
-		return obj.ColSpace, nil
+		return obj.ColSpace, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(float64)
+	fc.Result = res
+	return ec.marshalNFloat2float64(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_Grid_col_space(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Grid",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type Float does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _Grid_n_rows(ctx context.Context, field graphql.CollectedField, obj *model.Grid) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Grid_n_rows(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.NRows, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(int)
+	fc.Result = res
+	return ec.marshalNInt2int(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_Grid_n_rows(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Grid",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type Int does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _Grid_n_cols(ctx context.Context, field graphql.CollectedField, obj *model.Grid) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Grid_n_cols(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.NCols, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(int)
+	fc.Result = res
+	return ec.marshalNInt2int(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_Grid_n_cols(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Grid",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type Int does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _Matrix_id(ctx context.Context, field graphql.CollectedField, obj *model.Matrix) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Matrix_id(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.ID, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(string)
+	fc.Result = res
+	return ec.marshalNID2string(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_Matrix_id(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Matrix",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type ID does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _Matrix_name(ctx context.Context, field graphql.CollectedField, obj *model.Matrix) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Matrix_name(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.Name, nil
 	})
 	if err != nil {
 		ec.Error(ctx, err)
@@ -917,26 +1513,26 @@ func (ec *executionContext) _Grid_col_space(ctx context.Context, field graphql.C
 		}
 		return graphql.Null
 	}
-	res := resTmp.(float64)
+	res := resTmp.(string)
 	fc.Result = res
-	return ec.marshalNFloat2float64(ctx, field.Selections, res)
+	return ec.marshalNString2string(ctx, field.Selections, res)
 }
 
-func (ec *executionContext) fieldContext_Grid_col_space(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+func (ec *executionContext) fieldContext_Matrix_name(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
 	fc = &graphql.FieldContext{
-		Object:     "Grid",
+		Object:     "Matrix",
 		Field:      field,
 		IsMethod:   false,
 		IsResolver: false,
 		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
-			return nil, errors.New("field of type Float does not have child fields")
+			return nil, errors.New("field of type String does not have child fields")
 		},
 	}
 	return fc, nil
 }
 
-func (ec *executionContext) _Grid_n_rows(ctx context.Context, field graphql.CollectedField, obj *model.Grid) (ret graphql.Marshaler) {
-	fc, err := ec.fieldContext_Grid_n_rows(ctx, field)
+func (ec *executionContext) _Matrix_grids(ctx context.Context, field graphql.CollectedField, obj *model.Matrix) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Matrix_grids(ctx, field)
 	if err != nil {
 		return graphql.Null
 	}
@@ -949,7 +1545,7 @@ func (ec *executionContext) _Grid_n_rows(ctx context.Context, field graphql.Coll
 	}()
 	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
 		ctx = rctx // use context from middleware stack in children
-		return obj.NRows, nil
+		return obj.Grids, nil
 	})
 	if err != nil {
 		ec.Error(ctx, err)
@@ -961,26 +1557,42 @@ func (ec *executionContext) _Grid_n_rows(ctx context.Context, field graphql.Coll
 		}
 		return graphql.Null
 	}
-	res := resTmp.(int)
+	res := resTmp.([]*model.Grid)
 	fc.Result = res
-	return ec.marshalNInt2int(ctx, field.Selections, res)
+	return ec.marshalNGrid2ᚕᚖpipbotᚋgraphᚋmodelᚐGridᚄ(ctx, field.Selections, res)
 }
 
-func (ec *executionContext) fieldContext_Grid_n_rows(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+func (ec *executionContext) fieldContext_Matrix_grids(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
 	fc = &graphql.FieldContext{
-		Object:     "Grid",
+		Object:     "Matrix",
 		Field:      field,
 		IsMethod:   false,
 		IsResolver: false,
 		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
-			return nil, errors.New("field of type Int does not have child fields")
+			switch field.Name {
+			case "id":
+				return ec.fieldContext_Grid_id(ctx, field)
+			case "name":
+				return ec.fieldContext_Grid_name(ctx, field)
+			case "home":
+				return ec.fieldContext_Grid_home(ctx, field)
+			case "row_space":
+				return ec.fieldContext_Grid_row_space(ctx, field)
+			case "col_space":
+				return ec.fieldContext_Grid_col_space(ctx, field)
+			case "n_rows":
+				return ec.fieldContext_Grid_n_rows(ctx, field)
+			case "n_cols":
+				return ec.fieldContext_Grid_n_cols(ctx, field)
+			}
+			return nil, fmt.Errorf("no field named %q was found under type Grid", field.Name)
 		},
 	}
 	return fc, nil
 }
 
-func (ec *executionContext) _Grid_n_cols(ctx context.Context, field graphql.CollectedField, obj *model.Grid) (ret graphql.Marshaler) {
-	fc, err := ec.fieldContext_Grid_n_cols(ctx, field)
+func (ec *executionContext) _Mix_cycles(ctx context.Context, field graphql.CollectedField, obj *model.Mix) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Mix_cycles(ctx, field)
 	if err != nil {
 		return graphql.Null
 	}
@@ -993,7 +1605,7 @@ func (ec *executionContext) _Grid_n_cols(ctx context.Context, field graphql.Coll
 	}()
 	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
 		ctx = rctx // use context from middleware stack in children
-		return obj.NCols, nil
+		return obj.Cycles, nil
 	})
 	if err != nil {
 		ec.Error(ctx, err)
@@ -1010,9 +1622,9 @@ func (ec *executionContext) _Grid_n_cols(ctx context.Context, field graphql.Coll
 	return ec.marshalNInt2int(ctx, field.Selections, res)
 }
 
-func (ec *executionContext) fieldContext_Grid_n_cols(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+func (ec *executionContext) fieldContext_Mix_cycles(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
 	fc = &graphql.FieldContext{
-		Object:     "Grid",
+		Object:     "Mix",
 		Field:      field,
 		IsMethod:   false,
 		IsResolver: false,
@@ -1023,8 +1635,8 @@ func (ec *executionContext) fieldContext_Grid_n_cols(ctx context.Context, field
 	return fc, nil
 }
 
-func (ec *executionContext) _Matrix_id(ctx context.Context, field graphql.CollectedField, obj *model.Matrix) (ret graphql.Marshaler) {
-	fc, err := ec.fieldContext_Matrix_id(ctx, field)
+func (ec *executionContext) _Mix_volume(ctx context.Context, field graphql.CollectedField, obj *model.Mix) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Mix_volume(ctx, field)
 	if err != nil {
 		return graphql.Null
 	}
@@ -1037,7 +1649,7 @@ func (ec *executionContext) _Matrix_id(ctx context.Context, field graphql.Collec
 	}()
 	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
 		ctx = rctx // use context from middleware stack in children
-		return obj.ID, nil
+		return obj.Volume, nil
 	})
 	if err != nil {
 		ec.Error(ctx, err)
@@ -1049,26 +1661,26 @@ func (ec *executionContext) _Matrix_id(ctx context.Context, field graphql.Collec
 		}
 		return graphql.Null
 	}
-	res := resTmp.(string)
+	res := resTmp.(float64)
 	fc.Result = res
-	return ec.marshalNID2string(ctx, field.Selections, res)
+	return ec.marshalNFloat2float64(ctx, field.Selections, res)
 }
 
-func (ec *executionContext) fieldContext_Matrix_id(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+func (ec *executionContext) fieldContext_Mix_volume(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
 	fc = &graphql.FieldContext{
-		Object:     "Matrix",
+		Object:     "Mix",
 		Field:      field,
 		IsMethod:   false,
 		IsResolver: false,
 		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
-			return nil, errors.New("field of type ID does not have child fields")
+			return nil, errors.New("field of type Float does not have child fields")
 		},
 	}
 	return fc, nil
 }
 
-func (ec *executionContext) _Matrix_name(ctx context.Context, field graphql.CollectedField, obj *model.Matrix) (ret graphql.Marshaler) {
-	fc, err := ec.fieldContext_Matrix_name(ctx, field)
+func (ec *executionContext) _Mix_height(ctx context.Context, field graphql.CollectedField, obj *model.Mix) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Mix_height(ctx, field)
 	if err != nil {
 		return graphql.Null
 	}
@@ -1081,38 +1693,35 @@ func (ec *executionContext) _Matrix_name(ctx context.Context, field graphql.Coll
 	}()
 	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
 		ctx = rctx // use context from middleware stack in children
-		return obj.Name, nil
+		return obj.Height, nil
 	})
 	if err != nil {
 		ec.Error(ctx, err)
 		return graphql.Null
 	}
 	if resTmp == nil {
-		if !graphql.HasFieldError(ctx, fc) {
-			ec.Errorf(ctx, "must not be null")
-		}
 		return graphql.Null
 	}
-	res := resTmp.(string)
+	res := resTmp.(*float64)
 	fc.Result = res
-	return ec.marshalNString2string(ctx, field.Selections, res)
+	return ec.marshalOFloat2ᚖfloat64(ctx, field.Selections, res)
 }
 
-func (ec *executionContext) fieldContext_Matrix_name(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+func (ec *executionContext) fieldContext_Mix_height(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
 	fc = &graphql.FieldContext{
-		Object:     "Matrix",
+		Object:     "Mix",
 		Field:      field,
 		IsMethod:   false,
 		IsResolver: false,
 		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
-			return nil, errors.New("field of type String does not have child fields")
+			return nil, errors.New("field of type Float does not have child fields")
 		},
 	}
 	return fc, nil
 }
 
-func (ec *executionContext) _Matrix_grids(ctx context.Context, field graphql.CollectedField, obj *model.Matrix) (ret graphql.Marshaler) {
-	fc, err := ec.fieldContext_Matrix_grids(ctx, field)
+func (ec *executionContext) _Mix_rate(ctx context.Context, field graphql.CollectedField, obj *model.Mix) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Mix_rate(ctx, field)
 	if err != nil {
 		return graphql.Null
 	}
@@ -1125,47 +1734,28 @@ func (ec *executionContext) _Matrix_grids(ctx context.Context, field graphql.Col
 	}()
 	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
 		ctx = rctx // use context from middleware stack in children
-		return obj.Grids, nil
+		return obj.Rate, nil
 	})
 	if err != nil {
 		ec.Error(ctx, err)
 		return graphql.Null
 	}
 	if resTmp == nil {
-		if !graphql.HasFieldError(ctx, fc) {
-			ec.Errorf(ctx, "must not be null")
-		}
 		return graphql.Null
 	}
-	res := resTmp.([]*model.Grid)
+	res := resTmp.(*float64)
 	fc.Result = res
-	return ec.marshalNGrid2ᚕᚖpipbotᚋgraphᚋmodelᚐGridᚄ(ctx, field.Selections, res)
+	return ec.marshalOFloat2ᚖfloat64(ctx, field.Selections, res)
 }
 
-func (ec *executionContext) fieldContext_Matrix_grids(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+func (ec *executionContext) fieldContext_Mix_rate(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
 	fc = &graphql.FieldContext{
-		Object:     "Matrix",
+		Object:     "Mix",
 		Field:      field,
 		IsMethod:   false,
 		IsResolver: false,
 		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
-			switch field.Name {
-			case "id":
-				return ec.fieldContext_Grid_id(ctx, field)
-			case "name":
-				return ec.fieldContext_Grid_name(ctx, field)
-			case "home":
-				return ec.fieldContext_Grid_home(ctx, field)
-			case "row_space":
-				return ec.fieldContext_Grid_row_space(ctx, field)
-			case "col_space":
-				return ec.fieldContext_Grid_col_space(ctx, field)
-			case "n_rows":
-				return ec.fieldContext_Grid_n_rows(ctx, field)
-			case "n_cols":
-				return ec.fieldContext_Grid_n_cols(ctx, field)
-			}
-			return nil, fmt.Errorf("no field named %q was found under type Grid", field.Name)
+			return nil, errors.New("field of type Float does not have child fields")
 		},
 	}
 	return fc, nil
@@ -1354,6 +1944,8 @@ func (ec *executionContext) fieldContext_Mutation_createRecipe(ctx context.Conte
 				return ec.fieldContext_Recipe_transfers(ctx, field)
 			case "download":
 				return ec.fieldContext_Recipe_download(ctx, field)
+			case "estimate":
+				return ec.fieldContext_Recipe_estimate(ctx, field)
 			}
 			return nil, fmt.Errorf("no field named %q was found under type Recipe", field.Name)
 		},
@@ -1425,6 +2017,14 @@ func (ec *executionContext) fieldContext_Mutation_addTransfer(ctx context.Contex
 				return ec.fieldContext_Transfer_dest(ctx, field)
 			case "volume":
 				return ec.fieldContext_Transfer_volume(ctx, field)
+			case "tipPolicy":
+				return ec.fieldContext_Transfer_tipPolicy(ctx, field)
+			case "liquidClass":
+				return ec.fieldContext_Transfer_liquidClass(ctx, field)
+			case "mixBefore":
+				return ec.fieldContext_Transfer_mixBefore(ctx, field)
+			case "mixAfter":
+				return ec.fieldContext_Transfer_mixAfter(ctx, field)
 			}
 			return nil, fmt.Errorf("no field named %q was found under type Transfer", field.Name)
 		},
@@ -1879,6 +2479,8 @@ func (ec *executionContext) fieldContext_Query_recipes(ctx context.Context, fiel
 				return ec.fieldContext_Recipe_transfers(ctx, field)
 			case "download":
 				return ec.fieldContext_Recipe_download(ctx, field)
+			case "estimate":
+				return ec.fieldContext_Recipe_estimate(ctx, field)
 			}
 			return nil, fmt.Errorf("no field named %q was found under type Recipe", field.Name)
 		},
@@ -1935,6 +2537,8 @@ func (ec *executionContext) fieldContext_Query_recipe(ctx context.Context, field
 				return ec.fieldContext_Recipe_transfers(ctx, field)
 			case "download":
 				return ec.fieldContext_Recipe_download(ctx, field)
+			case "estimate":
+				return ec.fieldContext_Recipe_estimate(ctx, field)
 			}
 			return nil, fmt.Errorf("no field named %q was found under type Recipe", field.Name)
 		},
@@ -2140,7 +2744,171 @@ func (ec *executionContext) _Recipe_name(ctx context.Context, field graphql.Coll
 	}()
 	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
 		ctx = rctx // use context from middleware stack in children
-		return obj.Name, nil
+		return obj.Name, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(string)
+	fc.Result = res
+	return ec.marshalNString2string(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_Recipe_name(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Recipe",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type String does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _Recipe_matrix(ctx context.Context, field graphql.CollectedField, obj *model.Recipe) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Recipe_matrix(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.Matrix, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.(*model.Matrix)
+	fc.Result = res
+	return ec.marshalNMatrix2ᚖpipbotᚋgraphᚋmodelᚐMatrix(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_Recipe_matrix(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Recipe",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			switch field.Name {
+			case "id":
+				return ec.fieldContext_Matrix_id(ctx, field)
+			case "name":
+				return ec.fieldContext_Matrix_name(ctx, field)
+			case "grids":
+				return ec.fieldContext_Matrix_grids(ctx, field)
+			}
+			return nil, fmt.Errorf("no field named %q was found under type Matrix", field.Name)
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _Recipe_transfers(ctx context.Context, field graphql.CollectedField, obj *model.Recipe) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Recipe_transfers(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.Transfers, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.([]*model.Transfer)
+	fc.Result = res
+	return ec.marshalNTransfer2ᚕᚖpipbotᚋgraphᚋmodelᚐTransferᚄ(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_Recipe_transfers(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Recipe",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			switch field.Name {
+			case "id":
+				return ec.fieldContext_Transfer_id(ctx, field)
+			case "sampleId":
+				return ec.fieldContext_Transfer_sampleId(ctx, field)
+			case "name":
+				return ec.fieldContext_Transfer_name(ctx, field)
+			case "group":
+				return ec.fieldContext_Transfer_group(ctx, field)
+			case "source":
+				return ec.fieldContext_Transfer_source(ctx, field)
+			case "dest":
+				return ec.fieldContext_Transfer_dest(ctx, field)
+			case "volume":
+				return ec.fieldContext_Transfer_volume(ctx, field)
+			case "tipPolicy":
+				return ec.fieldContext_Transfer_tipPolicy(ctx, field)
+			case "liquidClass":
+				return ec.fieldContext_Transfer_liquidClass(ctx, field)
+			case "mixBefore":
+				return ec.fieldContext_Transfer_mixBefore(ctx, field)
+			case "mixAfter":
+				return ec.fieldContext_Transfer_mixAfter(ctx, field)
+			}
+			return nil, fmt.Errorf("no field named %q was found under type Transfer", field.Name)
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _Recipe_download(ctx context.Context, field graphql.CollectedField, obj *model.Recipe) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Recipe_download(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.Download, nil
 	})
 	if err != nil {
 		ec.Error(ctx, err)
@@ -2157,7 +2925,7 @@ func (ec *executionContext) _Recipe_name(ctx context.Context, field graphql.Coll
 	return ec.marshalNString2string(ctx, field.Selections, res)
 }
 
-func (ec *executionContext) fieldContext_Recipe_name(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+func (ec *executionContext) fieldContext_Recipe_download(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
 	fc = &graphql.FieldContext{
 		Object:     "Recipe",
 		Field:      field,
@@ -2170,8 +2938,8 @@ func (ec *executionContext) fieldContext_Recipe_name(ctx context.Context, field
 	return fc, nil
 }
 
-func (ec *executionContext) _Recipe_matrix(ctx context.Context, field graphql.CollectedField, obj *model.Recipe) (ret graphql.Marshaler) {
-	fc, err := ec.fieldContext_Recipe_matrix(ctx, field)
+func (ec *executionContext) _Recipe_estimate(ctx context.Context, field graphql.CollectedField, obj *model.Recipe) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Recipe_estimate(ctx, field)
 	if err != nil {
 		return graphql.Null
 	}
@@ -2184,7 +2952,7 @@ func (ec *executionContext) _Recipe_matrix(ctx context.Context, field graphql.Co
 	}()
 	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
 		ctx = rctx // use context from middleware stack in children
-		return obj.Matrix, nil
+		return ec.resolvers.Recipe().Estimate(rctx, obj)
 	})
 	if err != nil {
 		ec.Error(ctx, err)
@@ -2196,34 +2964,40 @@ func (ec *executionContext) _Recipe_matrix(ctx context.Context, field graphql.Co
 		}
 		return graphql.Null
 	}
-	res := resTmp.(*model.Matrix)
+	res := resTmp.(*model.Estimate)
 	fc.Result = res
-	return ec.marshalNMatrix2ᚖpipbotᚋgraphᚋmodelᚐMatrix(ctx, field.Selections, res)
+	return ec.marshalNEstimate2ᚖpipbotᚋgraphᚋmodelᚐEstimate(ctx, field.Selections, res)
 }
 
-func (ec *executionContext) fieldContext_Recipe_matrix(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+func (ec *executionContext) fieldContext_Recipe_estimate(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
 	fc = &graphql.FieldContext{
 		Object:     "Recipe",
 		Field:      field,
-		IsMethod:   false,
-		IsResolver: false,
+		IsMethod:   true,
+		IsResolver: true,
 		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
 			switch field.Name {
-			case "id":
-				return ec.fieldContext_Matrix_id(ctx, field)
-			case "name":
-				return ec.fieldContext_Matrix_name(ctx, field)
-			case "grids":
-				return ec.fieldContext_Matrix_grids(ctx, field)
+			case "transfers":
+				return ec.fieldContext_Estimate_transfers(ctx, field)
+			case "tipsNeeded":
+				return ec.fieldContext_Estimate_tipsNeeded(ctx, field)
+			case "tipsAvailable":
+				return ec.fieldContext_Estimate_tipsAvailable(ctx, field)
+			case "rackSwaps":
+				return ec.fieldContext_Estimate_rackSwaps(ctx, field)
+			case "sources":
+				return ec.fieldContext_Estimate_sources(ctx, field)
+			case "seconds":
+				return ec.fieldContext_Estimate_seconds(ctx, field)
 			}
-			return nil, fmt.Errorf("no field named %q was found under type Matrix", field.Name)
+			return nil, fmt.Errorf("no field named %q was found under type Estimate", field.Name)
 		},
 	}
 	return fc, nil
 }
 
-func (ec *executionContext) _Recipe_transfers(ctx context.Context, field graphql.CollectedField, obj *model.Recipe) (ret graphql.Marshaler) {
-	fc, err := ec.fieldContext_Recipe_transfers(ctx, field)
+func (ec *executionContext) _SourceVolume_source(ctx context.Context, field graphql.CollectedField, obj *model.SourceVolume) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_SourceVolume_source(ctx, field)
 	if err != nil {
 		return graphql.Null
 	}
@@ -2236,7 +3010,7 @@ func (ec *executionContext) _Recipe_transfers(ctx context.Context, field graphql
 	}()
 	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
 		ctx = rctx // use context from middleware stack in children
-		return obj.Transfers, nil
+		return obj.Source, nil
 	})
 	if err != nil {
 		ec.Error(ctx, err)
@@ -2248,42 +3022,26 @@ func (ec *executionContext) _Recipe_transfers(ctx context.Context, field graphql
 		}
 		return graphql.Null
 	}
-	res := resTmp.([]*model.Transfer)
+	res := resTmp.(string)
 	fc.Result = res
-	return ec.marshalNTransfer2ᚕᚖpipbotᚋgraphᚋmodelᚐTransferᚄ(ctx, field.Selections, res)
+	return ec.marshalNString2string(ctx, field.Selections, res)
 }
 
-func (ec *executionContext) fieldContext_Recipe_transfers(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+func (ec *executionContext) fieldContext_SourceVolume_source(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
 	fc = &graphql.FieldContext{
-		Object:     "Recipe",
+		Object:     "SourceVolume",
 		Field:      field,
 		IsMethod:   false,
 		IsResolver: false,
 		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
-			switch field.Name {
-			case "id":
-				return ec.fieldContext_Transfer_id(ctx, field)
-			case "sampleId":
-				return ec.fieldContext_Transfer_sampleId(ctx, field)
-			case "name":
-				return ec.fieldContext_Transfer_name(ctx, field)
-			case "group":
-				return ec.fieldContext_Transfer_group(ctx, field)
-			case "source":
-				return ec.fieldContext_Transfer_source(ctx, field)
-			case "dest":
-				return ec.fieldContext_Transfer_dest(ctx, field)
-			case "volume":
-				return ec.fieldContext_Transfer_volume(ctx, field)
-			}
-			return nil, fmt.Errorf("no field named %q was found under type Transfer", field.Name)
+			return nil, errors.New("field of type String does not have child fields")
 		},
 	}
 	return fc, nil
 }
 
-func (ec *executionContext) _Recipe_download(ctx context.Context, field graphql.CollectedField, obj *model.Recipe) (ret graphql.Marshaler) {
-	fc, err := ec.fieldContext_Recipe_download(ctx, field)
+func (ec *executionContext) _SourceVolume_volume(ctx context.Context, field graphql.CollectedField, obj *model.SourceVolume) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_SourceVolume_volume(ctx, field)
 	if err != nil {
 		return graphql.Null
 	}
@@ -2296,7 +3054,7 @@ func (ec *executionContext) _Recipe_download(ctx context.Context, field graphql.
 	}()
 	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
 		ctx = rctx // use context from middleware stack in children
-		return obj.Download, nil
+		return obj.Volume, nil
 	})
 	if err != nil {
 		ec.Error(ctx, err)
@@ -2308,19 +3066,19 @@ func (ec *executionContext) _Recipe_download(ctx context.Context, field graphql.
 		}
 		return graphql.Null
 	}
-	res := resTmp.(string)
+	res := resTmp.(float64)
 	fc.Result = res
-	return ec.marshalNString2string(ctx, field.Selections, res)
+	return ec.marshalNFloat2float64(ctx, field.Selections, res)
 }
 
-func (ec *executionContext) fieldContext_Recipe_download(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+func (ec *executionContext) fieldContext_SourceVolume_volume(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
 	fc = &graphql.FieldContext{
-		Object:     "Recipe",
+		Object:     "SourceVolume",
 		Field:      field,
 		IsMethod:   false,
 		IsResolver: false,
 		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
-			return nil, errors.New("field of type String does not have child fields")
+			return nil, errors.New("field of type Float does not have child fields")
 		},
 	}
 	return fc, nil
@@ -2644,6 +3402,190 @@ func (ec *executionContext) fieldContext_Transfer_volume(ctx context.Context, fi
 	return fc, nil
 }
 
+func (ec *executionContext) _Transfer_tipPolicy(ctx context.Context, field graphql.CollectedField, obj *model.Transfer) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Transfer_tipPolicy(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.TipPolicy, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*string)
+	fc.Result = res
+	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_Transfer_tipPolicy(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Transfer",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type String does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _Transfer_liquidClass(ctx context.Context, field graphql.CollectedField, obj *model.Transfer) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Transfer_liquidClass(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.LiquidClass, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*string)
+	fc.Result = res
+	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_Transfer_liquidClass(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Transfer",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type String does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _Transfer_mixBefore(ctx context.Context, field graphql.CollectedField, obj *model.Transfer) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Transfer_mixBefore(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.MixBefore, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*model.Mix)
+	fc.Result = res
+	return ec.marshalOMix2ᚖpipbotᚋgraphᚋmodelᚐMix(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_Transfer_mixBefore(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Transfer",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			switch field.Name {
+			case "cycles":
+				return ec.fieldContext_Mix_cycles(ctx, field)
+			case "volume":
+				return ec.fieldContext_Mix_volume(ctx, field)
+			case "height":
+				return ec.fieldContext_Mix_height(ctx, field)
+			case "rate":
+				return ec.fieldContext_Mix_rate(ctx, field)
+			}
+			return nil, fmt.Errorf("no field named %q was found under type Mix", field.Name)
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _Transfer_mixAfter(ctx context.Context, field graphql.CollectedField, obj *model.Transfer) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Transfer_mixAfter(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (interface{}, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.MixAfter, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*model.Mix)
+	fc.Result = res
+	return ec.marshalOMix2ᚖpipbotᚋgraphᚋmodelᚐMix(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_Transfer_mixAfter(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Transfer",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			switch field.Name {
+			case "cycles":
+				return ec.fieldContext_Mix_cycles(ctx, field)
+			case "volume":
+				return ec.fieldContext_Mix_volume(ctx, field)
+			case "height":
+				return ec.fieldContext_Mix_height(ctx, field)
+			case "rate":
+				return ec.fieldContext_Mix_rate(ctx, field)
+			}
+			return nil, fmt.Errorf("no field named %q was found under type Mix", field.Name)
+		},
+	}
+	return fc, nil
+}
+
 func (ec *executionContext) ___Directive_name(ctx context.Context, field graphql.CollectedField, obj *introspection.Directive) (ret graphql.Marshaler) {
 	fc, err := ec.fieldContext___Directive_name(ctx, field)
 	if err != nil {
@@ -4526,12 +5468,68 @@ func (ec *executionContext) unmarshalInputNewMatrix(ctx context.Context, obj int
 		case "grids":
 			var err error
 
-			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("grids"))
-			data, err := ec.unmarshalONewGrid2ᚕᚖpipbotᚋgraphᚋmodelᚐNewGridᚄ(ctx, v)
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("grids"))
+			data, err := ec.unmarshalONewGrid2ᚕᚖpipbotᚋgraphᚋmodelᚐNewGridᚄ(ctx, v)
+			if err != nil {
+				return it, err
+			}
+			it.Grids = data
+		}
+	}
+
+	return it, nil
+}
+
+func (ec *executionContext) unmarshalInputNewMix(ctx context.Context, obj interface{}) (model.NewMix, error) {
+	var it model.NewMix
+	asMap := map[string]interface{}{}
+	for k, v := range obj.(map[string]interface{}) {
+		asMap[k] = v
+	}
+
+	fieldsInOrder := [...]string{"cycles", "volume", "height", "rate"}
+	for _, k := range fieldsInOrder {
+		v, ok := asMap[k]
+		if !ok {
+			continue
+		}
+		switch k {
+		case "cycles":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("cycles"))
+			data, err := ec.unmarshalNInt2int(ctx, v)
+			if err != nil {
+				return it, err
+			}
+			it.Cycles = data
+		case "volume":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("volume"))
+			data, err := ec.unmarshalNFloat2float64(ctx, v)
+			if err != nil {
+				return it, err
+			}
+			it.Volume = data
+		case "height":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("height"))
+			data, err := ec.unmarshalOFloat2ᚖfloat64(ctx, v)
+			if err != nil {
+				return it, err
+			}
+			it.Height = data
+		case "rate":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("rate"))
+			data, err := ec.unmarshalOFloat2ᚖfloat64(ctx, v)
 			if err != nil {
 				return it, err
 			}
-			it.Grids = data
+			it.Rate = data
 		}
 	}
 
@@ -4686,7 +5684,7 @@ func (ec *executionContext) unmarshalInputNewTransfer(ctx context.Context, obj i
 		asMap[k] = v
 	}
 
-	fieldsInOrder := [...]string{"sampleId", "name", "group", "source", "dest", "volume"}
+	fieldsInOrder := [...]string{"sampleId", "name", "group", "source", "dest", "volume", "tipPolicy", "liquidClass", "mixBefore", "mixAfter"}
 	for _, k := range fieldsInOrder {
 		v, ok := asMap[k]
 		if !ok {
@@ -4747,6 +5745,42 @@ func (ec *executionContext) unmarshalInputNewTransfer(ctx context.Context, obj i
 				return it, err
 			}
 			it.Volume = data
+		case "tipPolicy":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("tipPolicy"))
+			data, err := ec.unmarshalOString2ᚖstring(ctx, v)
+			if err != nil {
+				return it, err
+			}
+			it.TipPolicy = data
+		case "liquidClass":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("liquidClass"))
+			data, err := ec.unmarshalOString2ᚖstring(ctx, v)
+			if err != nil {
+				return it, err
+			}
+			it.LiquidClass = data
+		case "mixBefore":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("mixBefore"))
+			data, err := ec.unmarshalONewMix2ᚖpipbotᚋgraphᚋmodelᚐNewMix(ctx, v)
+			if err != nil {
+				return it, err
+			}
+			it.MixBefore = data
+		case "mixAfter":
+			var err error
+
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("mixAfter"))
+			data, err := ec.unmarshalONewMix2ᚖpipbotᚋgraphᚋmodelᚐNewMix(ctx, v)
+			if err != nil {
+				return it, err
+			}
+			it.MixAfter = data
 		}
 	}
 
@@ -4761,6 +5795,70 @@ func (ec *executionContext) unmarshalInputNewTransfer(ctx context.Context, obj i
 
 // region    **************************** object.gotpl ****************************
 
+var estimateImplementors = []string{"Estimate"}
+
+func (ec *executionContext) _Estimate(ctx context.Context, sel ast.SelectionSet, obj *model.Estimate) graphql.Marshaler {
+	fields := graphql.CollectFields(ec.OperationContext, sel, estimateImplementors)
+
+	out := graphql.NewFieldSet(fields)
+	deferred := make(map[string]*graphql.FieldSet)
+	for i, field := range fields {
+		switch field.Name {
+		case "__typename":
+			out.Values[i] = graphql.MarshalString("Estimate")
+		case "transfers":
+			out.Values[i] = ec._Estimate_transfers(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		case "tipsNeeded":
+			out.Values[i] = ec._Estimate_tipsNeeded(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		case "tipsAvailable":
+			out.Values[i] = ec._Estimate_tipsAvailable(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		case "rackSwaps":
+			out.Values[i] = ec._Estimate_rackSwaps(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		case "sources":
+			out.Values[i] = ec._Estimate_sources(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		case "seconds":
+			out.Values[i] = ec._Estimate_seconds(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		default:
+			panic("unknown field " + strconv.Quote(field.Name))
+		}
+	}
+	out.Dispatch(ctx)
+	if out.Invalids > 0 {
+		return graphql.Null
+	}
+
+	atomic.AddInt32(&ec.deferred, int32(len(deferred)))
+
+	for label, dfs := range deferred {
+		ec.processDeferredGroup(graphql.DeferredGroup{
+			Label:    label,
+			Path:     graphql.GetPath(ctx),
+			FieldSet: dfs,
+			Context:  ctx,
+		})
+	}
+
+	return out
+}
+
 var gridImplementors = []string{"Grid"}
 
 func (ec *executionContext) _Grid(ctx context.Context, sel ast.SelectionSet, obj *model.Grid) graphql.Marshaler {
@@ -4879,6 +5977,54 @@ func (ec *executionContext) _Matrix(ctx context.Context, sel ast.SelectionSet, o
 	return out
 }
 
+var mixImplementors = []string{"Mix"}
+
+func (ec *executionContext) _Mix(ctx context.Context, sel ast.SelectionSet, obj *model.Mix) graphql.Marshaler {
+	fields := graphql.CollectFields(ec.OperationContext, sel, mixImplementors)
+
+	out := graphql.NewFieldSet(fields)
+	deferred := make(map[string]*graphql.FieldSet)
+	for i, field := range fields {
+		switch field.Name {
+		case "__typename":
+			out.Values[i] = graphql.MarshalString("Mix")
+		case "cycles":
+			out.Values[i] = ec._Mix_cycles(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		case "volume":
+			out.Values[i] = ec._Mix_volume(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		case "height":
+			out.Values[i] = ec._Mix_height(ctx, field, obj)
+		case "rate":
+			out.Values[i] = ec._Mix_rate(ctx, field, obj)
+		default:
+			panic("unknown field " + strconv.Quote(field.Name))
+		}
+	}
+	out.Dispatch(ctx)
+	if out.Invalids > 0 {
+		return graphql.Null
+	}
+
+	atomic.AddInt32(&ec.deferred, int32(len(deferred)))
+
+	for label, dfs := range deferred {
+		ec.processDeferredGroup(graphql.DeferredGroup{
+			Label:    label,
+			Path:     graphql.GetPath(ctx),
+			FieldSet: dfs,
+			Context:  ctx,
+		})
+	}
+
+	return out
+}
+
 var mutationImplementors = []string{"Mutation"}
 
 func (ec *executionContext) _Mutation(ctx context.Context, sel ast.SelectionSet) graphql.Marshaler {
@@ -5199,25 +6345,105 @@ func (ec *executionContext) _Recipe(ctx context.Context, sel ast.SelectionSet, o
 		case "id":
 			out.Values[i] = ec._Recipe_id(ctx, field, obj)
 			if out.Values[i] == graphql.Null {
-				out.Invalids++
+				atomic.AddUint32(&out.Invalids, 1)
 			}
 		case "name":
 			out.Values[i] = ec._Recipe_name(ctx, field, obj)
 			if out.Values[i] == graphql.Null {
-				out.Invalids++
+				atomic.AddUint32(&out.Invalids, 1)
 			}
 		case "matrix":
 			out.Values[i] = ec._Recipe_matrix(ctx, field, obj)
 			if out.Values[i] == graphql.Null {
-				out.Invalids++
+				atomic.AddUint32(&out.Invalids, 1)
 			}
 		case "transfers":
 			out.Values[i] = ec._Recipe_transfers(ctx, field, obj)
 			if out.Values[i] == graphql.Null {
-				out.Invalids++
+				atomic.AddUint32(&out.Invalids, 1)
 			}
 		case "download":
 			out.Values[i] = ec._Recipe_download(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				atomic.AddUint32(&out.Invalids, 1)
+			}
+		case "estimate":
+			field := field
+
+			innerFunc := func(ctx context.Context, fs *graphql.FieldSet) (res graphql.Marshaler) {
+				defer func() {
+					if r := recover(); r != nil {
+						ec.Error(ctx, ec.Recover(ctx, r))
+					}
+				}()
+				res = ec._Recipe_estimate(ctx, field, obj)
+				if res == graphql.Null {
+					atomic.AddUint32(&fs.Invalids, 1)
+				}
+				return res
+			}
+
+			if field.Deferrable != nil {
+				dfs, ok := deferred[field.Deferrable.Label]
+				di := 0
+				if ok {
+					dfs.AddField(field)
+					di = len(dfs.Values) - 1
+				} else {
+					dfs = graphql.NewFieldSet([]graphql.CollectedField{field})
+					deferred[field.Deferrable.Label] = dfs
+				}
+				dfs.Concurrently(di, func(ctx context.Context) graphql.Marshaler {
+					return innerFunc(ctx, dfs)
+				})
+
+				// don't run the out.Concurrently() call below
+				out.Values[i] = graphql.Null
+				continue
+			}
+
+			out.Concurrently(i, func(ctx context.Context) graphql.Marshaler { return innerFunc(ctx, out) })
+		default:
+			panic("unknown field " + strconv.Quote(field.Name))
+		}
+	}
+	out.Dispatch(ctx)
+	if out.Invalids > 0 {
+		return graphql.Null
+	}
+
+	atomic.AddInt32(&ec.deferred, int32(len(deferred)))
+
+	for label, dfs := range deferred {
+		ec.processDeferredGroup(graphql.DeferredGroup{
+			Label:    label,
+			Path:     graphql.GetPath(ctx),
+			FieldSet: dfs,
+			Context:  ctx,
+		})
+	}
+
+	return out
+}
+
+var sourceVolumeImplementors = []string{"SourceVolume"}
+
+func (ec *executionContext) _SourceVolume(ctx context.Context, sel ast.SelectionSet, obj *model.SourceVolume) graphql.Marshaler {
+	fields := graphql.CollectFields(ec.OperationContext, sel, sourceVolumeImplementors)
+
+	out := graphql.NewFieldSet(fields)
+	deferred := make(map[string]*graphql.FieldSet)
+	for i, field := range fields {
+		switch field.Name {
+		case "__typename":
+			out.Values[i] = graphql.MarshalString("SourceVolume")
+		case "source":
+			out.Values[i] = ec._SourceVolume_source(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		case "volume":
+			out.Values[i] = ec._SourceVolume_volume(ctx, field, obj)
 			if out.Values[i] == graphql.Null {
 				out.Invalids++
 			}
@@ -5284,6 +6510,14 @@ func (ec *executionContext) _Transfer(ctx context.Context, sel ast.SelectionSet,
 			if out.Values[i] == graphql.Null {
 				out.Invalids++
 			}
+		case "tipPolicy":
+			out.Values[i] = ec._Transfer_tipPolicy(ctx, field, obj)
+		case "liquidClass":
+			out.Values[i] = ec._Transfer_liquidClass(ctx, field, obj)
+		case "mixBefore":
+			out.Values[i] = ec._Transfer_mixBefore(ctx, field, obj)
+		case "mixAfter":
+			out.Values[i] = ec._Transfer_mixAfter(ctx, field, obj)
 		default:
 			panic("unknown field " + strconv.Quote(field.Name))
 		}
@@ -5648,6 +6882,20 @@ func (ec *executionContext) marshalNBoolean2bool(ctx context.Context, sel ast.Se
 	return res
 }
 
+func (ec *executionContext) marshalNEstimate2pipbotᚋgraphᚋmodelᚐEstimate(ctx context.Context, sel ast.SelectionSet, v model.Estimate) graphql.Marshaler {
+	return ec._Estimate(ctx, sel, &v)
+}
+
+func (ec *executionContext) marshalNEstimate2ᚖpipbotᚋgraphᚋmodelᚐEstimate(ctx context.Context, sel ast.SelectionSet, v *model.Estimate) graphql.Marshaler {
+	if v == nil {
+		if !graphql.HasFieldError(ctx, graphql.GetFieldContext(ctx)) {
+			ec.Errorf(ctx, "the requested element is null which the schema does not allow")
+		}
+		return graphql.Null
+	}
+	return ec._Estimate(ctx, sel, v)
+}
+
 func (ec *executionContext) unmarshalNFloat2float64(ctx context.Context, v interface{}) (float64, error) {
 	res, err := graphql.UnmarshalFloatContext(ctx, v)
 	return res, graphql.ErrorOnPath(ctx, err)
@@ -5927,6 +7175,60 @@ func (ec *executionContext) marshalNRecipe2ᚖpipbotᚋgraphᚋmodelᚐRecipe(ct
 	return ec._Recipe(ctx, sel, v)
 }
 
+func (ec *executionContext) marshalNSourceVolume2ᚕᚖpipbotᚋgraphᚋmodelᚐSourceVolumeᚄ(ctx context.Context, sel ast.SelectionSet, v []*model.SourceVolume) graphql.Marshaler {
+	ret := make(graphql.Array, len(v))
+	var wg sync.WaitGroup
+	isLen1 := len(v) == 1
+	if !isLen1 {
+		wg.Add(len(v))
+	}
+	for i := range v {
+		i := i
+		fc := &graphql.FieldContext{
+			Index:  &i,
+			Result: &v[i],
+		}
+		ctx := graphql.WithFieldContext(ctx, fc)
+		f := func(i int) {
+			defer func() {
+				if r := recover(); r != nil {
+					ec.Error(ctx, ec.Recover(ctx, r))
+					ret = nil
+				}
+			}()
+			if !isLen1 {
+				defer wg.Done()
+			}
+			ret[i] = ec.marshalNSourceVolume2ᚖpipbotᚋgraphᚋmodelᚐSourceVolume(ctx, sel, v[i])
+		}
+		if isLen1 {
+			f(i)
+		} else {
+			go f(i)
+		}
+
+	}
+	wg.Wait()
+
+	for _, e := range ret {
+		if e == graphql.Null {
+			return graphql.Null
+		}
+	}
+
+	return ret
+}
+
+func (ec *executionContext) marshalNSourceVolume2ᚖpipbotᚋgraphᚋmodelᚐSourceVolume(ctx context.Context, sel ast.SelectionSet, v *model.SourceVolume) graphql.Marshaler {
+	if v == nil {
+		if !graphql.HasFieldError(ctx, graphql.GetFieldContext(ctx)) {
+			ec.Errorf(ctx, "the requested element is null which the schema does not allow")
+		}
+		return graphql.Null
+	}
+	return ec._SourceVolume(ctx, sel, v)
+}
+
 func (ec *executionContext) unmarshalNString2string(ctx context.Context, v interface{}) (string, error) {
 	res, err := graphql.UnmarshalString(v)
 	return res, graphql.ErrorOnPath(ctx, err)
@@ -6279,6 +7581,29 @@ func (ec *executionContext) marshalOBoolean2ᚖbool(ctx context.Context, sel ast
 	return res
 }
 
+func (ec *executionContext) unmarshalOFloat2ᚖfloat64(ctx context.Context, v interface{}) (*float64, error) {
+	if v == nil {
+		return nil, nil
+	}
+	res, err := graphql.UnmarshalFloatContext(ctx, v)
+	return &res, graphql.ErrorOnPath(ctx, err)
+}
+
+func (ec *executionContext) marshalOFloat2ᚖfloat64(ctx context.Context, sel ast.SelectionSet, v *float64) graphql.Marshaler {
+	if v == nil {
+		return graphql.Null
+	}
+	res := graphql.MarshalFloatContext(*v)
+	return graphql.WrapContextMarshaler(ctx, res)
+}
+
+func (ec *executionContext) marshalOMix2ᚖpipbotᚋgraphᚋmodelᚐMix(ctx context.Context, sel ast.SelectionSet, v *model.Mix) graphql.Marshaler {
+	if v == nil {
+		return graphql.Null
+	}
+	return ec._Mix(ctx, sel, v)
+}
+
 func (ec *executionContext) unmarshalONewGrid2ᚕᚖpipbotᚋgraphᚋmodelᚐNewGridᚄ(ctx context.Context, v interface{}) ([]*model.NewGrid, error) {
 	if v == nil {
 		return nil, nil
@@ -6299,6 +7624,14 @@ func (ec *executionContext) unmarshalONewGrid2ᚕᚖpipbotᚋgraphᚋmodelᚐNew
 	return res, nil
 }
 
+func (ec *executionContext) unmarshalONewMix2ᚖpipbotᚋgraphᚋmodelᚐNewMix(ctx context.Context, v interface{}) (*model.NewMix, error) {
+	if v == nil {
+		return nil, nil
+	}
+	res, err := ec.unmarshalInputNewMix(ctx, v)
+	return &res, graphql.ErrorOnPath(ctx, err)
+}
+
 func (ec *executionContext) unmarshalONewTransfer2ᚕᚖpipbotᚋgraphᚋmodelᚐNewTransferᚄ(ctx context.Context, v interface{}) ([]*model.NewTransfer, error) {
 	if v == nil {
 		return nil, nil
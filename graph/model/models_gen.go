@@ -2,6 +2,16 @@
 
 package model
 
+type Estimate struct {
+	Transfers     int             `json:"transfers"`
+	TipsNeeded    int             `json:"tipsNeeded"`
+	TipsAvailable int             `json:"tipsAvailable"`
+	RackSwaps     int             `json:"rackSwaps"`
+	Sources       []*SourceVolume `json:"sources"`
+	// How long the moves take, not counting waits for racks to be replaced.
+	Seconds float64 `json:"seconds"`
+}
+
 type Grid struct {
 	ID       string    `json:"id"`
 	Name     string    `json:"name"`
@@ -18,6 +28,13 @@ type Matrix struct {
 	Grids []*Grid `json:"grids"`
 }
 
+type Mix struct {
+	Cycles int      `json:"cycles"`
+	Volume float64  `json:"volume"`
+	Height *float64 `json:"height,omitempty"`
+	Rate   *float64 `json:"rate,omitempty"`
+}
+
 type NewGrid struct {
 	MatrixID string       `json:"matrixId"`
 	Name     string       `json:"name"`
@@ -33,6 +50,13 @@ type NewMatrix struct {
 	Grids []*NewGrid `json:"grids,omitempty"`
 }
 
+type NewMix struct {
+	Cycles int      `json:"cycles"`
+	Volume float64  `json:"volume"`
+	Height *float64 `json:"height,omitempty"`
+	Rate   *float64 `json:"rate,omitempty"`
+}
+
 type NewNode struct {
 	Grid     string `json:"grid"`
 	Position string `json:"position"`
@@ -52,12 +76,16 @@ type NewRecipe struct {
 }
 
 type NewTransfer struct {
-	SampleID string   `json:"sampleId"`
-	Name     *string  `json:"name,omitempty"`
-	Group    *string  `json:"group,omitempty"`
-	Source   *NewNode `json:"source"`
-	Dest     *NewNode `json:"dest"`
-	Volume   float64  `json:"volume"`
+	SampleID    string   `json:"sampleId"`
+	Name        *string  `json:"name,omitempty"`
+	Group       *string  `json:"group,omitempty"`
+	Source      *NewNode `json:"source"`
+	Dest        *NewNode `json:"dest"`
+	Volume      float64  `json:"volume"`
+	TipPolicy   *string  `json:"tipPolicy,omitempty"`
+	LiquidClass *string  `json:"liquidClass,omitempty"`
+	MixBefore   *NewMix  `json:"mixBefore,omitempty"`
+	MixAfter    *NewMix  `json:"mixAfter,omitempty"`
 }
 
 type Node struct {
@@ -78,14 +106,26 @@ type Recipe struct {
 	Matrix    *Matrix     `json:"matrix"`
 	Transfers []*Transfer `json:"transfers"`
 	Download  string      `json:"download"`
+	// What running the recipe on its own matrix, with the bot's tips, would take.
+	Estimate *Estimate `json:"estimate"`
+}
+
+type SourceVolume struct {
+	// The well drawn from, as matrix:well.
+	Source string  `json:"source"`
+	Volume float64 `json:"volume"`
 }
 
 type Transfer struct {
-	ID       string  `json:"id"`
-	SampleID string  `json:"sampleId"`
-	Name     *string `json:"name,omitempty"`
-	Group    *string `json:"group,omitempty"`
-	Source   *Node   `json:"source"`
-	Dest     *Node   `json:"dest"`
-	Volume   float64 `json:"volume"`
+	ID          string  `json:"id"`
+	SampleID    string  `json:"sampleId"`
+	Name        *string `json:"name,omitempty"`
+	Group       *string `json:"group,omitempty"`
+	Source      *Node   `json:"source"`
+	Dest        *Node   `json:"dest"`
+	Volume      float64 `json:"volume"`
+	TipPolicy   *string `json:"tipPolicy,omitempty"`
+	LiquidClass *string `json:"liquidClass,omitempty"`
+	MixBefore   *Mix    `json:"mixBefore,omitempty"`
+	MixAfter    *Mix    `json:"mixAfter,omitempty"`
 }
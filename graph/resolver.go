@@ -1,6 +1,9 @@
 package graph
 
-import "pipbot/db"
+import (
+	"pipbot/db"
+	"pipbot/pipbot"
+)
 
 // This file will not be regenerated automatically.
 //
@@ -8,4 +11,16 @@ import "pipbot/db"
 
 type Resolver struct {
 	*db.Client
+	// Bot opens a bot to plan recipes on for their estimates. Its tip racks
+	// and speeds are used with the recipe's own grids, and it is never moved.
+	// The built-in deck on a Simulator is used if Bot is nil.
+	Bot func() (*pipbot.PipBot, error)
+}
+
+// bot opens a bot with Bot, or the default one.
+func (r *Resolver) bot() (*pipbot.PipBot, error) {
+	if r.Bot == nil {
+		return pipbot.NewPipBotOn(pipbot.NewSimulator(nil)), nil
+	}
+	return r.Bot()
 }
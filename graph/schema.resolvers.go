@@ -7,6 +7,7 @@ package graph
 import (
 	"context"
 	"pipbot/graph/model"
+	"pipbot/pipbot"
 )
 
 // CreateMatrix is the resolver for the createMatrix field.
@@ -49,11 +50,52 @@ func (r *queryResolver) Recipe(ctx context.Context, id string) (*model.Recipe, e
 	return r.Client.Recipe(ctx, id)
 }
 
+// Estimate is the resolver for the estimate field.
+func (r *recipeResolver) Estimate(ctx context.Context, obj *model.Recipe) (*model.Estimate, error) {
+	bot, err := r.bot()
+	if err != nil {
+		return nil, err
+	}
+	defer bot.Close()
+	if obj.Matrix != nil {
+		grids := obj.Matrix.Grids
+		if len(grids) == 0 {
+			if grids, err = r.Client.Grids(ctx, obj.Matrix.ID); err != nil {
+				return nil, err
+			}
+		}
+		bot.Layout = bot.Layout.WithGrids(grids)
+	}
+	if err = bot.Prepare(); err != nil {
+		return nil, err
+	}
+	est, err := bot.EstimateRecipe(&pipbot.Recipe{File: obj.Name, Transfers: obj.Transfers})
+	if err != nil {
+		return nil, err
+	}
+	res := &model.Estimate{
+		Transfers:     est.Transfers,
+		TipsNeeded:    est.TipsNeeded,
+		TipsAvailable: est.TipsAvailable,
+		RackSwaps:     est.RackSwaps,
+		Sources:       make([]*model.SourceVolume, 0, len(est.Sources)),
+		Seconds:       est.Duration.Seconds(),
+	}
+	for _, s := range est.Sources {
+		res.Sources = append(res.Sources, &model.SourceVolume{Source: s.Source, Volume: float64(s.Volume)})
+	}
+	return res, nil
+}
+
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
+// Recipe returns RecipeResolver implementation.
+func (r *Resolver) Recipe() RecipeResolver { return &recipeResolver{r} }
+
 type mutationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type recipeResolver struct{ *Resolver }
@@ -5,15 +5,38 @@ import (
 	"time"
 )
 
+// Position is a point in printer coordinates, in mm. It is passed by value:
+// its methods return new positions, so moving the bot never changes a
+// position kept somewhere else, such as a cell's.
 type Position struct {
 	X float32
 	Y float32
 	Z float32
 }
 
-func (p *Position) XY(rate ...float64) []byte {
+// WithZ returns p at height z.
+func (p Position) WithZ(z float32) Position {
+	p.Z = z
+	return p
+}
+
+// Add returns p moved by dx, dy and dz.
+func (p Position) Add(dx, dy, dz float32) Position {
+	return Position{X: p.X + dx, Y: p.Y + dy, Z: p.Z + dz}
+}
+
+// check reports whether p is inside the build volume.
+func (p Position) check() error {
+	if p.X < 0 || p.X > MaxX || p.Y < 0 || p.Y > MaxY || p.Z < 0 || p.Z > MaxZ {
+		return fmt.Errorf("%w: X%v Y%v Z%v is outside the build volume", ErrOutOfBounds, p.X, p.Y, p.Z)
+	}
+	return nil
+}
+
+// XY moves across to p at its height, at rate mm/min if one is given.
+func (p Position) XY(rate ...float64) []byte {
 	var fr float64
-	if len(rate) > 1 {
+	if len(rate) > 0 && rate[0] > 0 {
 		fr = rate[0]
 	} else {
 		fr = 1000
@@ -21,9 +44,10 @@ func (p *Position) XY(rate ...float64) []byte {
 	return []byte(fmt.Sprintf("G0 F%v X%v Y%v Z%v\n", fr, p.X, p.Y, p.Z))
 }
 
-func (p *Position) Low(rate ...float64) []byte {
+// Low moves up or down to p's height, at rate mm/min if one is given.
+func (p Position) Low(rate ...float64) []byte {
 	var fr float64
-	if len(rate) > 1 {
+	if len(rate) > 0 && rate[0] > 0 {
 		fr = rate[0]
 	} else {
 		fr = 1000
@@ -31,71 +55,443 @@ func (p *Position) Low(rate ...float64) []byte {
 	return []byte(fmt.Sprintf("G0 F%v Z%v\n", fr, p.Z))
 }
 
+// DefaultSlope is the plunger travel in mm per µL of the stock pipette.
+const DefaultSlope float32 = 0.1
+
+// checker is implemented by actions that can tell before anything moves
+// whether they will be able to run.
+type checker interface {
+	check() error
+}
+
+// path accumulates the moves of an action. It moves the same way GoTo does:
+// across at the current height, then up or down to the target. With a
+// planner it first lifts as high as the way across needs, and keeps the first
+// move that would crash in err.
+type path struct {
+	cur   Position
+	lines [][]byte
+	plan  *Planner
+	// speeds are on top of DefaultSpeeds.
+	speeds Speeds
+	// tip is set while a tip is on, and unsure until the path knows where the
+	// gantry is across the bed.
+	tip    bool
+	unsure bool
+	err    error
+}
+
+func (p *path) speed() Speeds {
+	return DefaultSpeeds.with(p.speeds)
+}
+
+func (p *path) goTo(to Position) {
+	p.move(to, p.speed().Z)
+}
+
+// move goes to to, coming up or down at the feed rate z.
+func (p *path) move(to Position, z float64) {
+	if p.plan != nil {
+		p.travel(to)
+	}
+	p.cur.X = to.X
+	p.cur.Y = to.Y
+	p.lines = append(p.lines, p.cur.XY(p.speed().XY))
+	p.cur.Z = to.Z
+	p.lines = append(p.lines, p.cur.Low(z))
+}
+
+// dive goes to to in a well whose rim is at rim, slowing to the approach feed
+// rate once below the rim.
+func (p *path) dive(to Position, rim float32) {
+	s := p.speed()
+	switch {
+	case s.approach() == s.Z || to.Z >= rim:
+		p.goTo(to)
+	case p.cur.Z > rim:
+		p.goTo(to.WithZ(rim))
+		p.move(to, s.approach())
+	default:
+		p.move(to, s.approach())
+	}
+}
+
+// using adds s to the speeds of p until the returned func is called.
+func (p *path) using(s Speeds) func() {
+	old := p.speeds
+	p.speeds = p.speeds.with(s)
+	return func() { p.speeds = old }
+}
+
+// lift moves straight up or down to z.
+func (p *path) lift(z float32) {
+	p.goTo(p.cur.WithZ(z))
+}
+
+func (p *path) send(format string, a ...any) {
+	p.lines = append(p.lines, []byte(fmt.Sprintf(format+"\n", a...)))
+}
+
+// add appends whole commands.
+func (p *path) add(cmds ...string) {
+	for _, c := range cmds {
+		p.lines = append(p.lines, []byte(c+"\n"))
+	}
+}
+
+// take returns the lines added since the last call.
+func (p *path) take() [][]byte {
+	l := p.lines
+	p.lines = nil
+	return l
+}
+
+// Transfer moves Volume from Src to Dest with Pipette. It picks up the tip at
+// Tip first, or keeps the one already loaded when Tip is nil, and ejects it
+// afterwards if TipChange is set. MixBefore mixes the source before it is
+// drawn from and MixAfter the destination once it is dispensed into. Class
+// says how to handle the liquid; the pipette's settings are used if it is nil.
 type Transfer struct {
+	Pipette   *Pipette
 	Tip       *Position
 	TipChange bool
-	Src       *Position
-	Dest      *Position
+	Src       Position
+	Dest      Position
 	Volume    float32
-	slope     float32
-	intercept float32
+	MixBefore *Mix
+	MixAfter  *Mix
+	Class     *LiquidClass
+	// tip is the rack and well Tip is in, for the tip inventory.
+	tip tipRef
+	// src and dest are the cells at Src and Dest, whose contents are kept up
+	// to date as the transfer runs. Either may be nil.
+	src, dest *Cell
+	// plan plans the moves when set, starting from from if that is known.
+	plan *Planner
+	from *Position
+	// speeds are the deck's speeds.
+	speeds Speeds
 }
 
-func (t *Transfer) getTip() {
+// NewTransfer returns a Transfer of vol from src to dest with p.
+func NewTransfer(p *Pipette, tip *Position, src, dest Position, vol float32, eject bool) *Transfer {
+	return &Transfer{
+		Pipette:   p,
+		Tip:       tip,
+		TipChange: eject,
+		Src:       src,
+		Dest:      dest,
+		Volume:    vol,
+	}
+}
 
+// phase is the G-code for one part of a transfer.
+type phase struct {
+	Phase
+	lines [][]byte
 }
 
-func (t *Transfer) drawFluid() {
+// start is where the gantry is when the transfer begins: from if it is
+// known, otherwise just clear of the deck without a tip, or clear of the wells
+// with one.
+func (t *Transfer) start() *path {
+	p := &path{plan: t.plan, speeds: t.speeds, tip: t.Tip == nil}
+	switch {
+	case t.from != nil:
+		p.cur = *t.from
+	case t.Tip != nil:
+		p.cur, p.unsure = Position{Z: TipOffClear}, true
+	default:
+		p.cur, p.unsure = Position{Z: TipOnClear}, true
+	}
+	return p
+}
 
+func (t *Transfer) getTip(p *path) {
+	if t.Tip == nil {
+		return
+	}
+	p.goTo(*t.Tip)
+	p.tip = true
+	p.leave(TipBoxClear)
 }
 
-func (t *Transfer) dispenseFluid() {
+// pipette is the pipette as the liquid class has it, held to the plunger
+// speed.
+func (t *Transfer) pipette() *Pipette {
+	s := t.speeds.with(t.class().Speeds)
+	return s.pipette(t.Class.pipette(t.Pipette))
+}
+
+// class is the liquid class, or one that changes nothing.
+func (t *Transfer) class() *LiquidClass {
+	if t.Class == nil {
+		return &LiquidClass{}
+	}
+	return t.Class
+}
 
+// drawFluid aspirates from the source. When the level of the liquid in it is
+// known the tip goes in below the surface and follows it down as it draws.
+func (t *Transfer) drawFluid(p *path) {
+	pip, c := t.pipette(), t.class()
+	defer p.using(c.Speeds)()
+	p.add(pip.ready()...)
+	depth := c.AspirateDepth
+	if _, ok := t.src.level(t.Src, 0); ok && depth == 0 {
+		depth = DefaultImmersion
+	}
+	at := t.src.immersed(t.Src, 0, depth)
+	end := t.src.immersed(t.Src, -t.Volume, depth)
+	p.dive(at, t.Src.Z)
+	t.mix(p, t.MixBefore, at, t.Src.Z-t.src.depth())
+	for i := 0; i < c.PreWet; i++ {
+		p.add(pip.mix(t.Volume, 0)...)
+	}
+	if end.Z < at.Z {
+		p.add(pip.follow(t.Volume, end.Z, at.Z-end.Z))
+		p.cur.Z = end.Z
+	} else {
+		p.add(pip.aspirate(t.Volume))
+	}
+	if c.Delay > 0 {
+		p.send("G4 P%v", int(c.Delay*1000))
+	}
+	p.leave(TipOnClear)
+	p.add(pip.airGap(t.Volume)...)
 }
 
-func (t *Transfer) ejectTip() {
+func (t *Transfer) dispenseFluid(p *path) {
+	pip, c := t.pipette(), t.class()
+	defer p.using(c.Speeds)()
+	at := t.dest.immersed(t.Dest, t.Volume, c.DispenseDepth)
+	p.dive(at, t.Dest.Z)
+	p.add(pip.dispense())
+	t.mix(p, t.MixAfter, at, t.Dest.Z-t.dest.depth())
+	t.touchTip(p, c.TouchTip)
+	p.leave(TipOnClear)
+	p.add(pip.blowout())
+}
 
+// touchTip touches the tip against the wall of the destination, by moving it
+// dist each way just inside the rim.
+func (t *Transfer) touchTip(p *path, dist float32) {
+	if dist <= 0 {
+		return
+	}
+	z := t.Dest.Z - touchTipDepth
+	p.lift(z)
+	for _, d := range [][2]float32{{dist, 0}, {-dist, 0}, {0, dist}, {0, -dist}, {0, 0}} {
+		p.goTo(t.Dest.Add(d[0], d[1], 0).WithZ(z))
+	}
+}
+
+func (t *Transfer) ejectTip(p *path) {
+	if !t.TipChange {
+		return
+	}
+	p.goTo(Position{X: EjectX, Y: p.cur.Y, Z: EjectZ})
+	p.tip = false
+	p.leave(TipOffClear)
+}
+
+// phases splits the G-code of t into its phases and returns where it leaves
+// the gantry. The error is the first move the planner refused, if any.
+func (t *Transfer) phases() ([]phase, Position, error) {
+	p := t.start()
+	var res []phase
+	var err error
+	for _, ph := range []struct {
+		Phase
+		run func(*path)
+	}{{PickingTip, t.getTip}, {Aspirating, t.drawFluid}, {Dispensing, t.dispenseFluid}, {Ejecting, t.ejectTip}} {
+		ph.run(p)
+		if ph.Phase == Ejecting {
+			p.send("M400")
+		}
+		if p.err != nil && err == nil {
+			err = fmt.Errorf("%v: %w", ph.Phase, p.err)
+		}
+		res = append(res, phase{Phase: ph.Phase, lines: p.take()})
+	}
+	return res, p.cur, err
+}
+
+// check reports whether the pipette can take the volume and every position
+// the transfer visits is reachable.
+func (t *Transfer) check() error {
+	if t.Class != nil {
+		if err := t.Class.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := t.speeds.Validate(); err != nil {
+		return err
+	}
+	if err := t.pipette().Check(t.Volume); err != nil {
+		return err
+	}
+	for _, m := range []*Mix{t.MixBefore, t.MixAfter} {
+		if m == nil {
+			continue
+		}
+		if err := m.validate(); err != nil {
+			return err
+		}
+		if err := t.Pipette.Check(m.Volume); err != nil {
+			return fmt.Errorf("mixing: %w", err)
+		}
+	}
+	pos := []Position{t.Src, t.Dest}
+	if t.Tip != nil {
+		pos = append(pos, *t.Tip)
+	}
+	for _, p := range pos {
+		if err := p.check(); err != nil {
+			return err
+		}
+	}
+	_, _, err := t.phases()
+	return err
 }
 
 func (t *Transfer) Bytes() [][]byte {
-	return [][]byte{}
+	phases, _, _ := t.phases()
+	var res [][]byte
+	for _, p := range phases {
+		res = append(res, p.lines...)
+	}
+	return res
 }
 
 func (t *Transfer) Finish() {
 
 }
 
+// Heat brings the hotend, or the bed if Bed is set, to Temperature. With a
+// Duration it waits for the temperature, holds it that long and switches the
+// heater off again; without one it only sets the target and moves on.
 type Heat struct {
 	Duration    time.Duration
 	Temperature float32
+	Bed         bool
 }
 
-func (t *Transfer) Heat() {
-
+func (h *Heat) Bytes() [][]byte {
+	set, wait := "M104", "M109"
+	if h.Bed {
+		set, wait = "M140", "M190"
+	}
+	if h.Duration <= 0 {
+		return [][]byte{[]byte(fmt.Sprintf("%v S%v\n", set, h.Temperature))}
+	}
+	return [][]byte{
+		[]byte(fmt.Sprintf("%v S%v\n", wait, h.Temperature)),
+		[]byte(fmt.Sprintf("G4 P%v\n", h.Duration.Milliseconds())),
+		[]byte(fmt.Sprintf("%v S0\n", set)),
+	}
 }
 
-func (h *Heat) Bytes() [][]byte {
-	return [][]byte{}
+func (h *Heat) Finish() {
+
 }
 
+// Shake rocks the bed, and the plates on it, back and forth along Y by
+// Amplitude either side of Center for Duration. If Temperature is set the bed
+// is heated to it first and switched off afterwards.
 type Shake struct {
 	Duration    time.Duration
 	Temperature float32
+	Center      *Position
+	Amplitude   float32
+	Rate        float64
+	// plan plans the way to Center when set, starting from from if that is
+	// known.
+	plan   *Planner
+	from   *Position
+	speeds Speeds
+}
+
+const (
+	// DefaultShakeAmplitude is how far either side of center Shake moves, in mm.
+	DefaultShakeAmplitude float32 = 5
+	// DefaultShakeRate is the feed rate of the shaking moves, in mm/min.
+	DefaultShakeRate float64 = 6000
+)
+
+// center is where the gantry rocks around, and where it is left.
+func (s *Shake) center() Position {
+	if s.Center == nil {
+		return Position{X: 10, Y: MaxY / 2, Z: TipBoxClear}
+	}
+	return *s.Center
+}
+
+// moves returns the path of s. Like a transfer it goes up before it goes
+// across when it does not know where the gantry is.
+func (s *Shake) moves() *path {
+	c := s.center()
+	amp, rate := s.Amplitude, s.Rate
+	if amp <= 0 {
+		amp = DefaultShakeAmplitude
+	}
+	if rate <= 0 {
+		rate = DefaultShakeRate
+	}
+	// one cycle runs out to +amp, across to -amp and back to center
+	period := time.Duration(float64(4*amp) / (rate / 60) * float64(time.Second))
+	cycles := int((s.Duration + period - 1) / period)
+
+	p := &path{plan: s.plan, speeds: s.speeds}
+	if s.Temperature > 0 {
+		p.send("M190 S%v", s.Temperature)
+	}
+	if s.from != nil {
+		p.cur = *s.from
+	} else {
+		p.cur, p.unsure = Position{Z: max32(TipOffClear, c.Z)}, true
+		p.lines = append(p.lines, p.cur.Low(p.speed().Z))
+	}
+	p.goTo(c)
+	if p.plan != nil && p.err == nil {
+		for _, y := range []float32{c.Y + amp, c.Y - amp} {
+			if p.err = p.plan.check(Position{X: c.X, Y: y, Z: c.Z}, false); p.err != nil {
+				break
+			}
+		}
+	}
+	for i := 0; i < cycles; i++ {
+		p.send("G0 F%v Y%v", rate, c.Y+amp)
+		p.send("G0 F%v Y%v", rate, c.Y-amp)
+	}
+	p.send("G0 F%v Y%v", rate, c.Y)
+	p.send("M400")
+	if s.Temperature > 0 {
+		p.send("M140 S0")
+	}
+	return p
+}
+
+// check reports a move of s that would crash.
+func (s *Shake) check() error {
+	return s.moves().err
 }
 
 func (s *Shake) Bytes() [][]byte {
-	return [][]byte{}
+	return s.moves().take()
 }
 
 func (s *Shake) Finish() {
 
 }
 
+// Action is anything the bot can do as a single step of a protocol.
 type Action interface {
 	Bytes() [][]byte
 	Finish()
 }
 
+// Do streams the G-code of a.
 func Do(a Action) <-chan []byte {
 	res := make(chan []byte)
 	go func(a Action) {
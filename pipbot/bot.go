@@ -3,25 +3,61 @@ package pipbot
 import (
 	"bufio"
 	"context"
-	"encoding/csv"
+	"errors"
 	"fmt"
-	"os"
+	"strconv"
+	"strings"
 	"sync/atomic"
+	"time"
 )
 
 type PipBot struct {
-	Layout     *Layout
-	Current    *Position
-	TipChannel <-chan *Position
-	client     *os.File
-	busy       atomic.Bool
-	rx         chan []byte
-	Rate       float64
-	TipStart   int
-	curTip     int
-	cushion    float32
-	steps      []*TransParams
-	hasTip     bool
+	Layout  *Layout
+	Pipette *Pipette
+	// Commanded is where the bot was last sent. It is not known until the
+	// bot has been homed.
+	Commanded Position
+	// Tips is the inventory of the tip racks. Init starts a fresh one if it is
+	// nil.
+	Tips   *TipInventory
+	client Transport
+	busy   atomic.Bool
+	rx     chan []byte
+	// Rate, if set, is the feed rate of GoTo and Jog in mm/min, in place of
+	// the deck's speeds.
+	Rate float64
+	// TipPolicy is how plans share tips between transfers that do not say.
+	// It is DefaultTipPolicy if empty.
+	TipPolicy TipPolicy
+	// Prompt asks the user to do something and returns once it is done. The
+	// printer waits for its button instead if it is nil.
+	Prompt   func(msg string) error
+	racks    []*Matrix
+	planTips *TipInventory
+	curTip   int
+	cushion  float32
+	hasTip   bool
+	homed    bool
+	state    State
+	// reported is where the printer last said it was, in reply to M114.
+	reported atomic.Pointer[Position]
+	// reports passes each position Listen reads on to Locate. It is closed
+	// when Listen stops.
+	reports chan Position
+}
+
+// State is what the bot knows about itself. After a failed transfer it
+// describes where the failure happened, so a caller can decide how to recover.
+type State struct {
+	// Commanded is where the bot was last sent and Reported where the
+	// printer last said it was; they differ while moves are still queued.
+	Commanded Position
+	Reported  Position
+	HasTip    bool
+	TipsUsed  int
+	Step      int
+	Phase     Phase
+	Err       error
 }
 
 const (
@@ -31,304 +67,537 @@ const (
 	CushionVolume float32 = 25
 )
 
-func (b *PipBot) SetupDispenser() {
-	m := []byte("M302 S1\n")
-
-	_, err := b.client.Write(m)
-	if err != nil {
-		panic(err)
+// send writes each command to the printer as its own line.
+func (b *PipBot) send(cmds ...string) error {
+	for _, c := range cmds {
+		if _, err := b.client.Write([]byte(c + "\n")); err != nil {
+			return err
+		}
 	}
-	m = []byte("M82\n")
+	return nil
+}
 
-	_, err = b.client.Write(m)
-	if err != nil {
-		panic(err)
-	}
-	m = []byte("G92 E0\n")
-	_, err = b.client.Write(m)
-	if err != nil {
-		panic(err)
-	}
+func (b *PipBot) SetupDispenser() error {
+	return b.send("M302 S1", "M82", "G92 E0")
 }
 
 const (
+	// OutFile is the port that writes the command stream to disk instead of a printer.
 	OutFile = "runFile.gcode"
 )
 
-// Init gets ready to run a protocol. Note that it automatically selects the last matrix as the tip matrix -- this will
-// not be hardcoded in less time pressed versions :)
-func (b *PipBot) Init() {
-	b.TipChannel = b.Layout.Matrices[3].Channel()
-	b.cushion = CushionVolume
-	for b.curTip != b.TipStart {
-		b.curTip++
-		_ = <-b.TipChannel
-	}
-	b.Home()
-	b.SetupDispenser()
-	target := b.Current
-	target.Z = TipOffClear
-	m := []byte("G92 E-30\n")
-	_, err := b.client.Write(m)
+// Prepare gets ready to plan a protocol without moving the printer, taking
+// tips from the deck's tip racks for the bot's pipette where the inventory
+// says they are left.
+func (b *PipBot) Prepare() error {
+	racks, err := b.Layout.TipBoxes(b.Pipette.Name)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	b.Dispense()
-	b.ResetCush()
-	m = []byte("G92 E0\n")
-	_, err = b.client.Write(m)
-	if err != nil {
-		panic(err)
+	b.racks = racks
+	if b.Tips == nil {
+		b.Tips = &TipInventory{}
 	}
-	b.Do(target)
+	b.planTips = b.Tips.clone()
+	return nil
 }
 
+// Init gets ready to run a protocol as Prepare does, then homes the printer
+// and sets up the plunger.
+func (b *PipBot) Init() error {
+	if err := b.Prepare(); err != nil {
+		return err
+	}
+	b.cushion = CushionVolume
+	if err := b.Home(); err != nil {
+		return err
+	}
+	if err := b.SetupDispenser(); err != nil {
+		return err
+	}
+	if err := b.send(b.speeds().setup()...); err != nil {
+		return err
+	}
+	target := b.Commanded.WithZ(TipOffClear)
+	if err := b.send("G92 E-30"); err != nil {
+		return err
+	}
+	if err := b.Dispense(); err != nil {
+		return err
+	}
+	if err := b.ResetCush(); err != nil {
+		return err
+	}
+	if err := b.send("G92 E0"); err != nil {
+		return err
+	}
+	return b.Do(target)
+}
+
+// Bytes returns the next line Listen read.
 func (b *PipBot) Bytes() []byte {
 	return <-b.rx
 }
 
-// getTip gets the next tip position and increments the counter
-func (b *PipBot) getTip() *Position {
-	b.curTip++
-	return <-b.TipChannel
+// State returns a snapshot of the bot's state.
+func (b *PipBot) State() State {
+	s := b.state
+	s.Commanded = b.Commanded
+	if p := b.reported.Load(); p != nil {
+		s.Reported = *p
+	}
+	s.HasTip = b.hasTip
+	s.TipsUsed = b.curTip
+	return s
 }
 
-func (b *PipBot) Transfer(src *Cell, dest *Cell, vol float32, eject bool) {
-	// get increment tip id and pickup the tip
-	if !b.hasTip {
-		t := b.getTip()
-		b.hasTip = true
-		b.Do(t)
-		t.Z = TipBoxClear
-		b.Do(t)
+// getTip plans which tip to pick up next. When the racks are used up it
+// returns a ReplaceTips to run first and counts them as full again after it.
+func (b *PipBot) getTip() (*Position, tipRef, *ReplaceTips, error) {
+	var replace *ReplaceTips
+	m, well, ok := b.planTips.next(b.racks)
+	if !ok && len(b.racks) > 0 {
+		replace = &ReplaceTips{}
+		for _, r := range b.racks {
+			replace.Racks = append(replace.Racks, r.Name)
+		}
+		b.planTips.Reset(replace.Racks...)
+		m, well, ok = b.planTips.next(b.racks)
+	}
+	if !ok {
+		return nil, tipRef{}, nil, fmt.Errorf("%w: no tip rack for pipette %q", ErrOutOfTips, b.Pipette.Name)
+	}
+	b.planTips.Use(m.Name, well)
+	c, err := m.Well(well)
+	if err != nil {
+		return nil, tipRef{}, nil, err
 	}
+	tip := c.Position
+	return &tip, tipRef{rack: m.Name, well: well}, replace, nil
+}
 
-	// go to source and insert into fluid
-	t := src.Position
-	tmp := t.Z
-	b.Do(t)
+// Transfer moves vol from src to dest, picking up a tip first if the bot does
+// not have one. If it fails the returned error is a *TransferError and State
+// reports the phase the transfer got to.
+func (b *PipBot) Transfer(src *Cell, dest *Cell, vol float32, eject bool) error {
+	t := NewTransfer(b.Pipette, nil, src.Position, dest.Position, vol, eject)
+	t.src, t.dest = src, dest
+	t.Class = src.Liquid
+	if err := (contents{}).move(src, dest, vol); err != nil {
+		b.state.Err = &TransferError{Step: b.state.Step, Phase: Aspirating, Err: err}
+		return b.state.Err
+	}
+	if !b.hasTip {
+		tip, ref, replace, err := b.getTip()
+		if err == nil && replace != nil {
+			err = b.exec(replace)
+		}
+		if err != nil {
+			b.state.Err = &TransferError{Step: b.state.Step, Phase: PickingTip, Err: err}
+			return b.state.Err
+		}
+		t.Tip, t.tip = tip, ref
+	}
+	return b.exec(t)
+}
 
-	// draw fluid
-	b.Pickup(vol)
+// Wait returns once the printer has finished every move sent so far.
+func (b *PipBot) Wait() error {
+	return b.send("M400")
+}
 
-	// remove from container
-	t.Z = TipOnClear
-	b.Do(t)
-	t.Z = tmp
-	// go to dest and insert into fluid
-	t = dest.Position
-	b.Do(t)
+// replaceTips waits for the racks in r to be replaced and then counts them as
+// full.
+func (b *PipBot) replaceTips(r *ReplaceTips) error {
+	if b.Prompt == nil {
+		if err := b.write(r.Bytes()); err != nil {
+			return err
+		}
+	} else {
+		if err := b.send("M400"); err != nil {
+			return err
+		}
+		if err := b.Prompt(r.message()); err != nil {
+			return err
+		}
+	}
+	b.Tips.Reset(r.Racks...)
+	return b.Tips.Save()
+}
 
-	// dispense fluid
-	b.Dispense()
-	// remove from container
-	t.Z = TipOnClear
-	b.Do(t)
+// runTransfer sends t one phase at a time so a failure can be pinned on the
+// phase it happened in. The contents of its cells follow the liquid.
+func (b *PipBot) runTransfer(t *Transfer) error {
+	t.plan, t.from, t.speeds = b.planner(), b.at(), b.speeds()
+	if err := t.check(); err != nil {
+		return err
+	}
+	var live contents
+	var load *Mixture
+	phases, end, _ := t.phases()
+	for _, p := range phases {
+		b.state.Phase = p.Phase
+		if err := b.write(p.lines); err != nil {
+			return err
+		}
+		switch {
+		case p.Phase == Aspirating && t.src != nil:
+			var err error
+			if load, err = live.draw(t.src, t.Volume); err != nil {
+				return err
+			}
+		case p.Phase == Dispensing && t.dest != nil && load != nil:
+			if err := live.pour(t.dest, load); err != nil {
+				return err
+			}
+		case p.Phase == PickingTip && t.Tip != nil:
+			b.hasTip = true
+			b.curTip++
+			if t.tip.rack != "" {
+				b.Tips.Use(t.tip.rack, t.tip.well)
+				if err := b.Tips.Save(); err != nil {
+					return err
+				}
+			}
+		case p.Phase == Ejecting && t.TipChange:
+			b.hasTip = false
+		}
+	}
+	b.Commanded = end
+	return nil
+}
 
-	b.ResetCush()
+func (b *PipBot) write(lines [][]byte) error {
+	for _, l := range lines {
+		if _, err := b.client.Write(l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	if eject {
-		b.Eject()
-		b.hasTip = false
+// exec runs a single action. Failed transfers are reported as a
+// *TransferError and recorded in State.
+func (b *PipBot) exec(a Action) error {
+	b.state.Err = nil
+	var err error
+	switch a := a.(type) {
+	case *Transfer:
+		if err = b.runTransfer(a); err != nil {
+			err = &TransferError{Step: b.state.Step, Phase: b.state.Phase, Err: err}
+		}
+	case *ReplaceTips:
+		err = b.replaceTips(a)
+	default:
+		// keep draining so the streaming goroutine can exit
+		for l := range Do(a) {
+			if err == nil {
+				_, err = b.client.Write(l)
+			}
+		}
+	}
+	if err != nil {
+		b.state.Err = err
+		return err
 	}
+	b.state.Phase = Idle
+	a.Finish()
+	return nil
+}
 
-	m := []byte("M400\n")
-	_, err := b.client.Write(m)
+// NewPipBot connects to the printer on port at baud. Passing OutFile (or any
+// other ".gcode" path) writes the commands to that file instead.
+func NewPipBot(port string, baud int) (*PipBot, error) {
+	t, err := Open(port, baud)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
+	return NewPipBotOn(t), nil
 }
 
-func NewPipBot(port string, baud int, firstTip int) *PipBot {
-	var err error
-	ret := &PipBot{
-		rx:       make(chan []byte),
-		Layout:   MakeGrid(),
-		TipStart: firstTip,
-		curTip:   0,
-		hasTip:   false,
+// NewPipBotOn returns a bot that talks to the printer over t, such as a
+// Simulator.
+func NewPipBotOn(t Transport) *PipBot {
+	return &PipBot{
+		rx:      make(chan []byte),
+		Layout:  MakeGrid(),
+		Pipette: StockPipette(),
+		client:  t,
 	}
+}
 
-	ret.client, err = os.Create(OutFile)
+func (b *PipBot) Close() error {
+	return b.client.Close()
+}
 
-	if err != nil {
-		panic(err)
+func (b *PipBot) Do(target Position) error {
+	return b.GoTo(target)
+}
+
+// Pickup draws volume into the tip. Volumes the pipette cannot handle are
+// rejected before the plunger moves.
+func (b *PipBot) Pickup(volume float32) error {
+	if err := b.Pipette.Check(volume); err != nil {
+		return err
 	}
-	return ret
+	if err := b.send(b.pipette().ready()...); err != nil {
+		return err
+	}
+	return b.send(b.pipette().aspirate(volume))
 }
 
-func (b *PipBot) Close() {
-	_ = b.client.Close()
+func (b *PipBot) Dispense() error {
+	return b.send(b.pipette().dispense())
 }
 
-func (b *PipBot) Do(target *Position) {
-	b.GoTo(target)
+// ResetCush pushes the plunger back down to E0, blowing out whatever is left.
+func (b *PipBot) ResetCush() error {
+	return b.send(b.pipette().blowout())
 }
 
-func (b *PipBot) Pickup(volume float32) {
-	travel := volume / 10
-	target := Position{Z: 85}
-	target.Z = target.Z + 1
-	m := []byte(fmt.Sprintf("G1 F500 E-%v\n", travel))
-	_, err := b.client.Write(m)
-	if err != nil {
-		panic(err)
+func (b *PipBot) Home() error {
+	if err := b.send("G28"); err != nil {
+		return err
 	}
+
+	b.Commanded = Position{}
+	b.homed = true
+	return nil
 }
 
-func (b *PipBot) Dispense() {
-	m := []byte("G1 F500 E0\n")
-	_, err := b.client.Write(m)
-	if err != nil {
-		panic(err)
+// Run executes actions in order and stops at the first failure. Every action
+// is checked before the first one starts, so a bad volume or position late in
+// a protocol, or a well that would run dry or overflow, is reported before
+// anything moves.
+func (b *PipBot) Run(actions []Action) error {
+	if err := b.check(actions); err != nil {
+		return err
 	}
+	for i, a := range actions {
+		fmt.Println(fmt.Sprintf("Step %v/%v", i, len(actions)))
+		b.state.Step = i
+		if err := b.exec(a); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (b *PipBot) ResetCush() {
-	m := []byte("G1 F500 E0\n")
-	_, err := b.client.Write(m)
-	if err != nil {
-		panic(err)
+// check routes actions and makes sure every step of them can run: no move
+// crashes and no well runs dry or overflows.
+func (b *PipBot) check(actions []Action) error {
+	b.route(actions)
+	for i, a := range actions {
+		if c, ok := a.(checker); ok {
+			if err := c.check(); err != nil {
+				return fmt.Errorf("step %v: %w", i, err)
+			}
+		}
 	}
+	return checkVolumes(actions)
 }
 
-func (b *PipBot) Home() {
-	m := []byte("G28\n")
-	_, err := b.client.Write(m)
-	if err != nil {
-		panic(err)
+// planner is the motion planner for the bot's deck and pipette.
+func (b *PipBot) planner() *Planner {
+	if b.Layout == nil {
+		return nil
 	}
+	return b.Layout.Planner(b.Pipette)
+}
 
-	b.Current = &Position{
-		X: 0,
-		Y: 0,
-		Z: 0,
+// speeds are the deck's speeds.
+func (b *PipBot) speeds() Speeds {
+	if b.Layout == nil {
+		return Speeds{}
 	}
+	return b.Layout.Speeds
 }
 
-type TransParams struct {
-	SrcRow int
-	SrcCol int
-	DstRow int
-	DstCol int
-	eject  bool
+// manual are the speeds of GoTo and Jog: the deck's, unless Rate is set.
+func (b *PipBot) manual() Speeds {
+	return b.speeds().with(Speeds{XY: b.Rate, Z: b.Rate})
 }
 
-func (b *PipBot) Run() {
-	for i, s := range b.steps {
-		fmt.Println(fmt.Sprintf("Step %v/%v", i, len(b.steps)))
-		b.Transfer(b.Layout.Matrices[2].Cells[s.SrcRow][s.SrcCol], b.Layout.Matrices[1].Cells[s.DstRow][s.DstCol], 100, s.eject)
-	}
+// pipette is the bot's pipette held to the deck's plunger speed.
+func (b *PipBot) pipette() *Pipette {
+	return b.speeds().pipette(b.Pipette)
 }
 
-func (b *PipBot) Plan(file string) {
-	b.steps = make([]*TransParams, 0)
-	f, err := os.Open(file)
-	if err != nil {
-		panic(err)
+// at is a copy of where the bot was sent, or nil if that is not known.
+func (b *PipBot) at() *Position {
+	if !b.homed {
+		return nil
 	}
+	p := b.Commanded
+	return &p
+}
 
-	defer func(f *os.File) {
-		_ = f.Close()
-	}(f)
+// Reported returns where the printer last said it was, and false if it has
+// not said since Listen started.
+func (b *PipBot) Reported() (Position, bool) {
+	if p := b.reported.Load(); p != nil {
+		return *p, true
+	}
+	return Position{}, false
+}
 
-	rdr := csv.NewReader(f)
-	rows, err := rdr.ReadAll()
-	if err != nil {
-		panic(err)
+// Locate asks the printer where it is and waits for Listen to read the
+// answer, for up to DefaultTimeout.
+func (b *PipBot) Locate() (Position, error) {
+	if b.reports == nil {
+		return Position{}, errors.New("locate: the printer is not being listened to")
+	}
+	// drop an answer nobody asked for
+	select {
+	case <-b.reports:
+	default:
+	}
+	if err := b.send("M114"); err != nil {
+		return Position{}, err
 	}
+	timer := time.NewTimer(DefaultTimeout)
+	defer timer.Stop()
+	select {
+	case p, ok := <-b.reports:
+		if !ok {
+			return Position{}, errors.New("locate: the printer stopped talking")
+		}
+		return p, nil
+	case <-timer.C:
+		return Position{}, fmt.Errorf("%w: no answer to M114 after %v", ErrTimeout, DefaultTimeout)
+	}
+}
 
-	for i, row := range rows {
-		for j, cell := range row[:12] {
-			eject := false
-			if j < 11 {
-				if row[j+1] != cell {
-					eject = true
-				}
-			}
-			if j == 11 {
-				if i != 7 {
-					if rows[i+1][0] != cell {
-						eject = true
-					}
-				}
-			}
-			switch cell {
-			case "Blue":
-				t := &TransParams{
-					SrcRow: 0,
-					SrcCol: 0,
-					DstRow: i,
-					DstCol: j,
-					eject:  eject,
-				}
-				b.steps = append(b.steps, t)
-			case "Red":
-				t := &TransParams{
-					SrcRow: 0,
-					SrcCol: 1,
-					DstRow: i,
-					DstCol: j,
-					eject:  eject,
-				}
-				b.steps = append(b.steps, t)
-			case "Orange":
-				t := &TransParams{
-					SrcRow: 0,
-					SrcCol: 2,
-					DstRow: i,
-					DstCol: j,
-					eject:  eject,
-				}
-				b.steps = append(b.steps, t)
-			}
+// route plans the moves of the transfers and shakes in actions, each from
+// where the one before leaves the gantry, so that a crash anywhere in them is
+// found before the first one runs. After anything else that moves the gantry
+// the next one starts from an unknown position.
+func (b *PipBot) route(actions []Action) {
+	from := b.at()
+	for _, a := range actions {
+		switch a := a.(type) {
+		case *Transfer:
+			a.plan, a.from, a.speeds = b.planner(), from, b.speeds()
+			_, end, _ := a.phases()
+			from = &end
+		case *Shake:
+			a.plan, a.from, a.speeds = b.planner(), from, b.speeds()
+			end := a.center()
+			from = &end
+		case *ReplaceTips, *Heat:
+		default:
+			from = nil
 		}
 	}
 }
 
-func (b *PipBot) GoTo(p *Position) {
-	target := b.Current
-	target.X = p.X
-	target.Y = p.Y
-	_, err := b.client.Write(target.XY(b.Rate))
+// Plan reads a recipe file, or a plate map if file is not a recipe, and turns
+// it into transfers. Tips are assigned as the plan is made, from the racks as
+// the inventory has them now.
+func (b *PipBot) Plan(file string) ([]Action, error) {
+	r, err := OpenRecipe(file, "")
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	target.Z = p.Z
-	_, err = b.client.Write(target.Low(b.Rate))
-	if err != nil {
-		panic(err)
+	if err = b.Prepare(); err != nil {
+		return nil, err
+	}
+	return b.PlanRecipe(r)
+}
+
+// GoTo moves to p, going up first if the way across needs it. Moves that
+// would crash the tip into labware or leave the build volume are refused
+// before anything is sent.
+func (b *PipBot) GoTo(p Position) error {
+	if err := p.check(); err != nil {
+		return err
+	}
+	path := &path{cur: b.Commanded, plan: b.planner(), speeds: b.manual(), tip: b.hasTip, unsure: !b.homed}
+	path.goTo(p)
+	if path.err != nil {
+		return path.err
+	}
+	if err := b.write(path.take()); err != nil {
+		return err
 	}
-	b.Current = target
+	b.Commanded = path.cur
+	return nil
 }
 
-func (b *PipBot) Eject() {
-	target := &Position{
-		X: 10,
-		Y: b.Current.Y,
-		Z: 154,
+func (b *PipBot) Eject() error {
+	target := Position{
+		X: EjectX,
+		Y: b.Commanded.Y,
+		Z: EjectZ,
 	}
-	b.Do(target)
-	target.Z = 85
-	b.Do(target)
+	if err := b.Do(target); err != nil {
+		return err
+	}
+	b.hasTip = false
+	return b.Do(target.WithZ(TipOffClear))
 }
 
+// Listen reads what the printer prints in the background, keeping Reported up
+// to date and answering Locate. Lines are queued for Bytes, and dropped once the queue is full so
+// that nobody reading them never holds up the printer.
 func (b *PipBot) Listen(ctx context.Context) bool {
-	b.rx = make(chan []byte)
+	b.rx = make(chan []byte, historySize)
+	b.reports = make(chan Position, 1)
 	scan := bufio.NewScanner(b.client)
 	cont := true
 	go func() {
 		defer close(b.rx)
+		defer close(b.reports)
 		for scan.Scan() {
 			select {
 			case <-ctx.Done():
 				cont = false
 			default:
-				bytes := scan.Bytes()
-				b.rx <- bytes
-				fmt.Println(string(bytes))
+				line := scan.Text()
+				if p, ok := parseReport(line); ok {
+					b.reported.Store(&p)
+					select {
+					case b.reports <- p:
+					default:
+					}
+				}
+				select {
+				case b.rx <- []byte(line):
+				default:
+				}
+				fmt.Println(line)
 			}
 		}
 	}()
 	return cont
 }
+
+// parseReport reads the position out of a printer's reply to M114, such as
+// "X:10.00 Y:20.00 Z:85.00 E:0.00 Count X:800 Y:1600 Z:34000".
+func parseReport(line string) (Position, bool) {
+	var p Position
+	line, _, _ = strings.Cut(line, " Count")
+	found := 0
+	for _, f := range strings.Fields(line) {
+		axis, v, ok := strings.Cut(f, ":")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseFloat(v, 32)
+		if err != nil {
+			return Position{}, false
+		}
+		switch axis {
+		case "X":
+			p.X = float32(n)
+		case "Y":
+			p.Y = float32(n)
+		case "Z":
+			p.Z = float32(n)
+		default:
+			continue
+		}
+		found++
+	}
+	return p, found == 3
+}
@@ -0,0 +1,113 @@
+package pipbot
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"pipbot/graph/model"
+)
+
+// positions copies where every cell of l is.
+func positions(l *Layout) [][][]Position {
+	var res [][][]Position
+	for _, m := range l.Matrices {
+		rows := make([][]Position, len(m.Cells))
+		for i, cells := range m.Cells {
+			for _, c := range cells {
+				rows[i] = append(rows[i], c.Position)
+			}
+		}
+		res = append(res, rows)
+	}
+	return res
+}
+
+func TestRunKeepsCellPositions(t *testing.T) {
+	b := NewPipBotOn(NewSimulator(nil))
+	want := positions(b.Layout)
+	if err := b.Init(); err != nil {
+		t.Fatal(err)
+	}
+	r := &Recipe{Transfers: []*model.Transfer{
+		{Source: &model.Node{Grid: "12", Position: "A1"}, Dest: &model.Node{Grid: "96", Position: "A1"}, Volume: 50, MixAfter: &model.Mix{Cycles: 2, Volume: 20}},
+		{Source: &model.Node{Grid: "12", Position: "B2"}, Dest: &model.Node{Grid: "96", Position: "H12"}, Volume: 30},
+		{Source: &model.Node{Grid: "96", Position: "A1"}, Dest: &model.Node{Grid: "Purp", Position: "A1"}, Volume: 10},
+	}}
+	actions, err := b.PlanRecipe(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = b.Run(actions); err != nil {
+		t.Fatal(err)
+	}
+	if got := positions(b.Layout); !reflect.DeepEqual(got, want) {
+		t.Errorf("cells moved during the run:\n%v\nwant\n%v", got, want)
+	}
+}
+
+// replies is a printer that prints out and takes any commands.
+type replies struct{ io.Reader }
+
+func (replies) Write(p []byte) (int, error) { return len(p), nil }
+func (replies) Close() error                { return nil }
+
+func TestListenWithoutReader(t *testing.T) {
+	out := strings.Repeat("echo:busy: processing\n", 2*historySize) +
+		"X:10.00 Y:20.00 Z:85.00 E:0.00 Count X:800 Y:1600 Z:34000\n"
+	b := NewPipBotOn(replies{strings.NewReader(out)})
+	b.Listen(context.Background())
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if p, ok := b.Reported(); ok {
+			if want := (Position{X: 10, Y: 20, Z: 85}); p != want {
+				t.Errorf("reported %v, want %v", p, want)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the position was not read with nobody reading the other lines")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// locator is a printer that answers M114 with where it is.
+type locator struct {
+	*io.PipeReader
+	w *io.PipeWriter
+}
+
+func (l locator) Write(p []byte) (int, error) {
+	if strings.HasPrefix(string(p), "M114") {
+		go l.w.Write([]byte("X:1.50 Y:2.00 Z:100.00 E:0.00 Count X:120 Y:160 Z:40000\nok\n"))
+	}
+	return len(p), nil
+}
+
+func (l locator) Close() error {
+	return l.w.Close()
+}
+
+func TestLocate(t *testing.T) {
+	r, w := io.Pipe()
+	b := NewPipBotOn(locator{r, w})
+	if _, err := b.Locate(); err == nil {
+		t.Error("located without listening")
+	}
+	b.Listen(context.Background())
+	p, err := b.Locate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Position{X: 1.5, Y: 2, Z: 100}); p != want {
+		t.Errorf("located at %v, want %v", p, want)
+	}
+	b.Close()
+	if _, err = b.Locate(); err == nil {
+		t.Error("located after the printer went away")
+	}
+}
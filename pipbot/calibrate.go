@@ -0,0 +1,416 @@
+package pipbot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WaterDensity is the density of water around room temperature in mg/µL.
+const WaterDensity float32 = 0.998
+
+// Measurement is one gravimetric calibration cycle: the volume that was asked
+// for, the plunger travel that was used for it and the mass that came out.
+type Measurement struct {
+	Nominal float32
+	Travel  float32
+	// Mass is in mg.
+	Mass float32
+}
+
+// VolumeStats summarises the cycles at one nominal volume. Mean and SD are
+// in µL, CV and Accuracy in percent. Accuracy is the systematic error of the
+// mean against the nominal volume.
+type VolumeStats struct {
+	Nominal  float32
+	N        int
+	Mean     float32
+	SD       float32
+	CV       float32
+	Accuracy float32
+}
+
+// Calibration is a fitted volume curve and how the pipette did against the
+// curve it was run with.
+type Calibration struct {
+	Curve Curve
+	Stats []VolumeStats
+}
+
+// FitCurve fits plunger travel against the delivered volume, converting mass
+// to volume with density in mg/µL. Linear fits need at least two distinct
+// nominal volumes; piecewise fits get a point per nominal volume.
+func FitCurve(ms []Measurement, density float32, piecewise bool) (*Calibration, error) {
+	if density <= 0 {
+		return nil, errors.New("density must be positive")
+	}
+	groups := make(map[float32][]Measurement)
+	for _, m := range ms {
+		groups[m.Nominal] = append(groups[m.Nominal], m)
+	}
+	if len(groups) < 2 {
+		return nil, errors.New("need measurements at two or more volumes")
+	}
+	nominals := make([]float32, 0, len(groups))
+	for n := range groups {
+		nominals = append(nominals, n)
+	}
+	sort.Slice(nominals, func(i, j int) bool { return nominals[i] < nominals[j] })
+
+	res := &Calibration{}
+	var points []CurvePoint
+	for _, n := range nominals {
+		g := groups[n]
+		var sum, travel float64
+		for _, m := range g {
+			sum += float64(m.Mass / density)
+			travel += float64(m.Travel)
+		}
+		mean := sum / float64(len(g))
+		var ss float64
+		for _, m := range g {
+			d := float64(m.Mass/density) - mean
+			ss += d * d
+		}
+		var sd float64
+		if len(g) > 1 {
+			sd = math.Sqrt(ss / float64(len(g)-1))
+		}
+		st := VolumeStats{
+			Nominal:  n,
+			N:        len(g),
+			Mean:     float32(mean),
+			SD:       float32(sd),
+			Accuracy: float32((mean - float64(n)) / float64(n) * 100),
+		}
+		if mean > 0 {
+			st.CV = float32(sd / mean * 100)
+		}
+		res.Stats = append(res.Stats, st)
+		points = append(points, CurvePoint{Volume: float32(mean), Travel: float32(travel / float64(len(g)))})
+	}
+
+	// the linear fit is kept alongside the points of a piecewise curve
+	var sx, sy, sxx, sxy float64
+	for _, m := range ms {
+		x, y := float64(m.Mass/density), float64(m.Travel)
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+	}
+	n := float64(len(ms))
+	den := n*sxx - sx*sx
+	if den == 0 {
+		return nil, errors.New("delivered volumes do not vary, cannot fit a slope")
+	}
+	res.Curve.Slope = float32((n*sxy - sx*sy) / den)
+	res.Curve.Intercept = float32((sy - float64(res.Curve.Slope)*sx) / n)
+	if res.Curve.Slope <= 0 {
+		return nil, fmt.Errorf("fitted slope %v is not positive", res.Curve.Slope)
+	}
+	if piecewise {
+		res.Curve.Points = points
+		if err := res.Curve.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// SavePipette writes p into the pipettes section of a deck file, replacing
+// the entry with the same name or adding one. YAML decks keep their comments
+// and layout; JSON decks are rewritten.
+func SavePipette(file string, p *Pipette) error {
+	return editDeck(file, func(d *Deck) error {
+		for i, o := range d.Pipettes {
+			if o.Name == p.Name {
+				d.Pipettes[i] = p
+				return nil
+			}
+		}
+		d.Pipettes = append(d.Pipettes, p)
+		return nil
+	}, func(root *yaml.Node) error {
+		entry := &yaml.Node{}
+		if err := entry.Encode(p); err != nil {
+			return err
+		}
+		tidy(entry)
+		list := section(root, "pipettes")
+		for i, item := range list.Content {
+			old := &Pipette{}
+			if item.Decode(old) == nil && old.Name == p.Name {
+				list.Content[i] = entry
+				return nil
+			}
+		}
+		list.Content = append(list.Content, entry)
+		return nil
+	})
+}
+
+// SaveMatrix writes where a matrix was found to be into its entry in a deck
+// file. The rest of the entry, such as its labware, is left alone.
+func SaveMatrix(file, name string, f GridFit) error {
+	return editDeck(file, func(d *Deck) error {
+		for i := range d.Matrices {
+			if c := &d.Matrices[i]; c.Name == name {
+				c.Home, c.RowSpace, c.ColSpace, c.Rotation, c.Skew = f.Home, f.RowSpace, f.ColSpace, f.Rotation, f.Skew
+				c.Transform = f.Transform
+				if f.Transform != nil {
+					c.Rotation, c.Skew = 0, 0
+				}
+				return nil
+			}
+		}
+		return fmt.Errorf("no matrix named %q on the deck", name)
+	}, func(root *yaml.Node) error {
+		for _, item := range section(root, "matrices").Content {
+			c := &MatrixConfig{}
+			if item.Decode(c) != nil || c.Name != name {
+				continue
+			}
+			// a transform says everything the angles would
+			rotation, skew := f.Rotation, f.Skew
+			if f.Transform != nil {
+				rotation, skew = 0, 0
+			}
+			for _, kv := range []struct {
+				key string
+				val any
+			}{
+				{"home", f.Home}, {"row_space", f.RowSpace}, {"col_space", f.ColSpace},
+				{"rotation", rotation}, {"skew", skew}, {"transform", f.Transform},
+			} {
+				if kv.val == float32(0) || kv.val == (*Transform)(nil) {
+					deleteKey(item, kv.key)
+					continue
+				}
+				if err := setKey(item, kv.key, kv.val); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return fmt.Errorf("no matrix named %q on the deck", name)
+	})
+}
+
+// editDeck rewrites a deck file. JSON decks go through jsonEdit and are
+// written back out whole; YAML decks are edited as a node tree by yamlEdit so
+// that comments and layout survive.
+func editDeck(file string, jsonEdit func(d *Deck) error, yamlEdit func(root *yaml.Node) error) error {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		d := &Deck{}
+		if err = json.Unmarshal(b, d); err != nil {
+			return fmt.Errorf("%v: %w", file, err)
+		}
+		if err = jsonEdit(d); err != nil {
+			return fmt.Errorf("%v: %w", file, err)
+		}
+		if b, err = json.MarshalIndent(d, "", "  "); err != nil {
+			return err
+		}
+		return os.WriteFile(file, append(b, '\n'), 0o644)
+	}
+
+	doc := &yaml.Node{}
+	if err = yaml.Unmarshal(b, doc); err != nil {
+		return fmt.Errorf("%v: %w", file, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("%v: deck is not a mapping", file)
+	}
+	if err = yamlEdit(doc.Content[0]); err != nil {
+		return fmt.Errorf("%v: %w", file, err)
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(f)
+	enc.SetIndent(2)
+	if err = enc.Encode(doc); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// section returns the sequence under key in a mapping node, adding an empty
+// one if there is none.
+func section(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	list := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, list)
+	return list
+}
+
+// setKey sets key in a mapping node to v, keeping its place if it is there.
+// Mappings are merged into the one already there key by key, which keeps
+// their style.
+func setKey(m *yaml.Node, key string, v any) error {
+	n := &yaml.Node{}
+	if err := n.Encode(v); err != nil {
+		return err
+	}
+	tidy(n)
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			merge(m.Content[i+1], n)
+			return nil
+		}
+	}
+	setNode(m, key, n)
+	return nil
+}
+
+// merge makes old say what n does.
+func merge(old, n *yaml.Node) {
+	if old.Kind != yaml.MappingNode || n.Kind != yaml.MappingNode {
+		n.Style |= old.Style & yaml.FlowStyle
+		*old = *n
+		return
+	}
+	for j := 0; j+1 < len(n.Content); j += 2 {
+		found := false
+		for i := 0; i+1 < len(old.Content); i += 2 {
+			if old.Content[i].Value == n.Content[j].Value {
+				merge(old.Content[i+1], n.Content[j+1])
+				found = true
+			}
+		}
+		if !found {
+			old.Content = append(old.Content, n.Content[j], n.Content[j+1])
+		}
+	}
+}
+
+// tidy writes n the way deck files are laid out by hand: mappings of plain
+// values, like positions, go on one line, and keys like "y", which YAML 1.1
+// would read as a bool, lose the quotes the encoder gives them. The encoder
+// puts quotes back where they are needed.
+func tidy(n *yaml.Node) {
+	leaf := n.Kind == yaml.MappingNode
+	for i, c := range n.Content {
+		if n.Kind == yaml.MappingNode && i%2 == 0 && c.Tag == "!!str" {
+			c.Style = 0
+		}
+		leaf = leaf && c.Kind == yaml.ScalarNode
+		tidy(c)
+	}
+	if leaf {
+		n.Style |= yaml.FlowStyle
+	}
+}
+
+// setNode sets key in a mapping node to n.
+func setNode(m *yaml.Node, key string, n *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = n
+			return
+		}
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, n)
+}
+
+// deleteKey removes key from a mapping node.
+func deleteKey(m *yaml.Node, key string) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// JogSteps are the step sizes, in mm, offered when jogging the gantry.
+var JogSteps = []float32{0.1, 1, 10}
+
+// Jog moves the gantry by dx, dy and dz from where it is.
+func (b *PipBot) Jog(dx, dy, dz float32) error {
+	target := b.Commanded.Add(dx, dy, dz)
+	target = Position{X: hundredths(target.X), Y: hundredths(target.Y), Z: hundredths(target.Z)}
+	if err := target.check(); err != nil {
+		return err
+	}
+	if _, err := b.client.Write(target.XY(DefaultSpeeds.with(b.manual()).XY)); err != nil {
+		return err
+	}
+	b.Commanded = target
+	return nil
+}
+
+// GridFit is where a matrix was measured to be on the bed. Transform is set
+// when enough wells were measured to fit one.
+type GridFit struct {
+	Home      Position
+	RowSpace  float32
+	ColSpace  float32
+	Rotation  float32
+	Skew      float32
+	Transform *Transform
+}
+
+// FitGrid works out the placement of m from the measured centers of its A1
+// well and the well in the opposite corner. Two wells cannot tell rotation
+// from skew, so the angle between them is taken as rotation and the spacings
+// keep the ratio m has. Measuring end, the last well of the first row, as well
+// fits a full Transform, which also takes up skew and tilt.
+func FitGrid(m *Matrix, a1, far Position, end *Position) (GridFit, error) {
+	res := GridFit{Home: a1, RowSpace: m.RowSpace, ColSpace: m.ColSpace}
+	rows, cols := float64(m.Rows-1), float64(m.Columns-1)
+	if rows == 0 && cols == 0 {
+		return res, fmt.Errorf("matrix %q has a single well, there is nothing to fit", m.Name)
+	}
+	if end != nil && rows > 0 && cols > 0 {
+		t, err := FitTransform([]GridPoint{
+			{At: a1},
+			{Col: m.Columns - 1, At: *end},
+			{Row: m.Rows - 1, Col: m.Columns - 1, At: far},
+		})
+		if err != nil {
+			return res, fmt.Errorf("matrix %q: %w", m.Name, err)
+		}
+		res = GridFit{Home: t.Origin, Transform: &t}
+		res.RowSpace, res.ColSpace = t.Spacing()
+		res.Rotation, res.Skew = t.Rotation()
+		return res, nil
+	}
+
+	cs, rs := float64(m.ColSpace), float64(m.RowSpace)
+	if cs <= 0 || rs <= 0 {
+		cs, rs = 1, 1
+	}
+	nx, ny := cols*cs, rows*rs
+	dx, dy := float64(far.X-a1.X), float64(far.Y-a1.Y)
+	if math.Hypot(dx, dy) == 0 {
+		return res, fmt.Errorf("matrix %q: A1 and %v were measured at the same place", m.Name, WellName(m.Rows-1, m.Columns-1))
+	}
+	scale := math.Hypot(dx, dy) / math.Hypot(nx, ny)
+	res.Rotation = degrees(math.Atan2(dy, dx) - math.Atan2(ny, nx))
+	if cols > 0 {
+		res.ColSpace = hundredths(float32(cs * scale))
+	}
+	if rows > 0 {
+		res.RowSpace = hundredths(float32(rs * scale))
+	}
+	return res, nil
+}
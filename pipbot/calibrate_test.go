@@ -0,0 +1,101 @@
+package pipbot
+
+import (
+	"math"
+	"testing"
+)
+
+// near reports whether got is within tol of want.
+func near(got, want, tol float32) bool {
+	return math.Abs(float64(got-want)) <= float64(tol)
+}
+
+// calibrationRun has three cycles at 10 and 100 µL of a liquid at 0.5 mg/µL,
+// with the travel on 0.1 mm/µL + 0.5 mm. The small volumes come out 10% over.
+func calibrationRun() []Measurement {
+	var ms []Measurement
+	for _, m := range []struct{ nominal, vol float32 }{
+		{10, 10}, {10, 11}, {10, 12},
+		{100, 98}, {100, 100}, {100, 102},
+	} {
+		ms = append(ms, Measurement{Nominal: m.nominal, Travel: 0.1*m.vol + 0.5, Mass: m.vol * 0.5})
+	}
+	return ms
+}
+
+func TestFitCurve(t *testing.T) {
+	c, err := FitCurve(calibrationRun(), 0.5, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(c.Curve.Slope, 0.1, 1e-4) || !near(c.Curve.Intercept, 0.5, 1e-3) {
+		t.Errorf("travel %v*vol + %v, want 0.1*vol + 0.5", c.Curve.Slope, c.Curve.Intercept)
+	}
+	if c.Curve.Points != nil {
+		t.Errorf("linear fit has points %v", c.Curve.Points)
+	}
+	want := []VolumeStats{
+		{Nominal: 10, N: 3, Mean: 11, SD: 1, CV: 9.09, Accuracy: 10},
+		{Nominal: 100, N: 3, Mean: 100, SD: 2, CV: 2, Accuracy: 0},
+	}
+	if len(c.Stats) != len(want) {
+		t.Fatalf("stats %+v, want %+v", c.Stats, want)
+	}
+	for i, w := range want {
+		g := c.Stats[i]
+		if g.Nominal != w.Nominal || g.N != w.N || !near(g.Mean, w.Mean, 1e-3) || !near(g.SD, w.SD, 1e-3) ||
+			!near(g.CV, w.CV, 0.01) || !near(g.Accuracy, w.Accuracy, 1e-3) {
+			t.Errorf("stats at %v µL: %+v, want %+v", w.Nominal, g, w)
+		}
+	}
+}
+
+func TestFitCurvePiecewise(t *testing.T) {
+	c, err := FitCurve(calibrationRun(), 0.5, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []CurvePoint{{Volume: 11, Travel: 1.6}, {Volume: 100, Travel: 10.5}}
+	if len(c.Curve.Points) != len(want) {
+		t.Fatalf("points %v, want %v", c.Curve.Points, want)
+	}
+	for i, w := range want {
+		if g := c.Curve.Points[i]; !near(g.Volume, w.Volume, 1e-3) || !near(g.Travel, w.Travel, 1e-3) {
+			t.Errorf("point %v: %v, want %v", i, g, w)
+		}
+	}
+	if !near(c.Curve.Slope, 0.1, 1e-4) {
+		t.Errorf("slope %v, want the linear fit kept alongside", c.Curve.Slope)
+	}
+}
+
+func TestFitCurveErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		ms      []Measurement
+		density float32
+		want    string
+	}{
+		{name: "no density", ms: calibrationRun(), density: 0, want: "density must be positive"},
+		{name: "one volume", ms: calibrationRun()[:3], density: 1, want: "need measurements at two or more volumes"},
+		{
+			name:    "same delivery",
+			ms:      []Measurement{{Nominal: 10, Travel: 1, Mass: 10}, {Nominal: 20, Travel: 2, Mass: 10}},
+			density: 1,
+			want:    "delivered volumes do not vary, cannot fit a slope",
+		},
+		{
+			name:    "falling travel",
+			ms:      []Measurement{{Nominal: 10, Travel: 2, Mass: 10}, {Nominal: 20, Travel: 1, Mass: 20}},
+			density: 1,
+			want:    "fitted slope -0.1 is not positive",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := FitCurve(tc.ms, tc.density, false)
+			if err == nil || err.Error() != tc.want {
+				t.Errorf("got %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
@@ -1,32 +1,47 @@
 package pipbot
 
-const Port = "COM5"
+// Port is where commands go when no port is given: OutFile, so nothing is
+// sent anywhere until a printer is named.
+const Port = OutFile
 
+// const Port = "COM5"
 // const Port = "/dev/cu.usbserial-1110"
 
 const Baud = 115200
 
+// Travel limits of the Ender 3 V2 the bot is built on. The axes run a little
+// past the 220 mm bed, which the tip box relies on.
+const (
+	MaxX float32 = 235
+	MaxY float32 = 235
+	MaxZ float32 = 250
+)
+
+// MakeGrid is the deck used when no deck file is given.
 func MakeGrid() *Layout {
 	ret := &Layout{
 		Matrices: make([]*Matrix, 4),
 	}
 
-	ret.Matrices[0] = NewMatrix(Unknown, "Purp", &Position{X: 29, Y: 17, Z: 80}, 42.5-29,
+	ret.Matrices[0] = NewMatrix(Unknown, "Purp", Position{X: 29, Y: 17, Z: 80}, 42.5-29,
 		42.5-29, 5, 16)
 
-	ret.Matrices[1] = NewMatrix(Unknown, "96", &Position{X: 35.5, Y: 86.5, Z: 74.5},
+	ret.Matrices[1] = NewMatrix(Unknown, "96", Position{X: 35.5, Y: 86.5, Z: 74.5},
 		9,
 		9, 8, 12)
 
-	ret.Matrices[2] = NewMatrix(Stock, "12", &Position{X: 46, Y: 178.5, Z: 75},
+	ret.Matrices[2] = NewMatrix(Stock, "12", Position{X: 46, Y: 178.5, Z: 75},
 		72-46,
 		72-46, 3, 4)
-	ret.Matrices[3] = NewMatrix(Tip, "tips", &Position{
+	ret.Matrices[3] = NewMatrix(Tip, "tips", Position{
 		X: 165,
 		Y: 103.5,
 		Z: 73.5,
 	}, 173.8-165, 173.8-165, 12, 8,
 	)
+	ret.Matrices[1].Role = Destination
+	ret.Matrices[2].Role = Source
+	ret.Matrices[3].Pipette = DefaultPipette
 
 	return ret
 }
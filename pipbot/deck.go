@@ -0,0 +1,321 @@
+package pipbot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPipette is the name of the pipette when a deck does not declare any.
+const DefaultPipette = "default"
+
+// MatrixConfig is how a Matrix is declared in a deck file. When Labware names
+// a library entry, the geometry it leaves out is taken from there, so
+//
+//	matrices:
+//	  - {name: plate, labware: corning_96_wellplate_360ul_flat, home: {x: 35.5, y: 86.5, z: 74.5}}
+//
+// is enough to place a 96 well plate.
+type MatrixConfig struct {
+	Name     string    `yaml:"name" json:"name"`
+	Labware  string    `yaml:"labware,omitempty" json:"labware,omitempty"`
+	Kind     *CellType `yaml:"kind,omitempty" json:"kind,omitempty"`
+	Role     Role      `yaml:"role,omitempty" json:"role,omitempty"`
+	Home     Position  `yaml:"home" json:"home"`
+	RowSpace float32   `yaml:"row_space" json:"row_space"`
+	ColSpace float32   `yaml:"col_space" json:"col_space"`
+	Rows     int       `yaml:"rows" json:"rows"`
+	Cols     int       `yaml:"cols" json:"cols"`
+	Depth    float32   `yaml:"depth,omitempty" json:"depth,omitempty"`
+	Rotation float32   `yaml:"rotation,omitempty" json:"rotation,omitempty"`
+	Skew     float32   `yaml:"skew,omitempty" json:"skew,omitempty"`
+	// Transform, when given, places the matrix instead of Home, the spacings
+	// and the angles.
+	Transform *Transform `yaml:"transform,omitempty" json:"transform,omitempty"`
+	Pipette   string     `yaml:"pipette,omitempty" json:"pipette,omitempty"`
+	// MaxVolume is how much each well holds in µL, when it is not what the
+	// labware says.
+	MaxVolume float32 `yaml:"max_volume,omitempty" json:"max_volume,omitempty"`
+	// Contents fills wells with stock before a run: µL by well name.
+	Contents map[string]float32 `yaml:"contents,omitempty" json:"contents,omitempty"`
+	// Concentrations are those of the stocks in Contents, such as "10 mM",
+	// by well name.
+	Concentrations map[string]Quantity `yaml:"concentrations,omitempty" json:"concentrations,omitempty"`
+	// LiquidClass is the liquid class of every well, and LiquidClasses that
+	// of single wells, by well name.
+	LiquidClass   string            `yaml:"liquid_class,omitempty" json:"liquid_class,omitempty"`
+	LiquidClasses map[string]string `yaml:"liquid_classes,omitempty" json:"liquid_classes,omitempty"`
+	// Clearance is the closest the tip may come to the bottom of a well, in
+	// mm, when following the liquid down.
+	Clearance float32 `yaml:"clearance,omitempty" json:"clearance,omitempty"`
+	// Top is the highest Z the tip can touch the matrix at, when something
+	// stands above its rims.
+	Top float32 `yaml:"top,omitempty" json:"top,omitempty"`
+}
+
+// Deck is the contents of a deck file: where the printer is and what sits on
+// its bed.
+//
+//	port: /dev/ttyUSB0
+//	baud: 115200
+//	matrices:
+//	  - name: tips
+//	    kind: tip
+//	    home: {x: 165, y: 103.5, z: 73.5}
+//	    row_space: 8.8
+//	    col_space: 8.8
+//	    rows: 12
+//	    cols: 8
+//	  - name: stocks
+//	    labware: opentrons_24_tuberack_eppendorf_1.5ml_safelock_snapcap
+//	    role: source
+//	    home: {x: 46, y: 178.5, z: 75}
+//	    contents: {A1: 1500, A2: 1500}
+//	    concentrations: {A1: 10 mM, A2: 2 mg/mL}
+//	    liquid_classes: {A2: glycerol}
+//
+// Source wells without contents are taken to hold whatever is drawn from
+// them.
+type Deck struct {
+	Port     string     `yaml:"port,omitempty" json:"port,omitempty"`
+	Baud     int        `yaml:"baud,omitempty" json:"baud,omitempty"`
+	Pipettes []*Pipette `yaml:"pipettes,omitempty" json:"pipettes,omitempty"`
+	// Labware lists Opentrons labware definition files to add to the library
+	// before the matrices are built. Relative paths are relative to the deck.
+	Labware []string `yaml:"labware,omitempty" json:"labware,omitempty"`
+	// LiquidClasses are added to the library of liquid classes before the
+	// matrices are built.
+	LiquidClasses []*LiquidClass `yaml:"liquid_classes,omitempty" json:"liquid_classes,omitempty"`
+	// TravelMargin is how far above the matrices the tip crosses the deck,
+	// in mm.
+	TravelMargin float32 `yaml:"travel_margin,omitempty" json:"travel_margin,omitempty"`
+	// Speeds are how fast the gantry moves, where DefaultSpeeds will not do.
+	Speeds   Speeds         `yaml:"speeds,omitempty" json:"speeds,omitempty"`
+	Matrices []MatrixConfig `yaml:"matrices" json:"matrices"`
+	dir      string
+}
+
+// LoadDeck reads a deck file. Files ending in .json are read as JSON, anything
+// else as YAML.
+func LoadDeck(file string) (*Deck, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	d := &Deck{}
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		err = json.Unmarshal(b, d)
+	} else {
+		err = yaml.Unmarshal(b, d)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", file, err)
+	}
+	d.dir = filepath.Dir(file)
+	return d, nil
+}
+
+// build makes the Matrix c declares, filling in from its labware.
+func (c MatrixConfig) build() (*Matrix, error) {
+	m, err := c.grid()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case c.Transform != nil:
+		m.SetTransform(*c.Transform)
+	case c.Rotation != 0 || c.Skew != 0:
+		m.Rotate(c.Rotation, c.Skew)
+	}
+	if c.MaxVolume > 0 {
+		m.SetMaxVolume(c.MaxVolume)
+	}
+	m.Top = c.Top
+	if c.Clearance > 0 {
+		w := &Well{}
+		if cell := m.Cells[0][0]; cell.Well != nil {
+			*w = *cell.Well
+		}
+		w.Clearance = c.Clearance
+		m.SetWell(w)
+	}
+	for well, vol := range c.Contents {
+		cell, err := m.Well(well)
+		if err == nil {
+			err = cell.Fill(vol)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("matrix %q: %v: %w", c.Name, well, err)
+		}
+	}
+	for well, conc := range c.Concentrations {
+		cell, err := m.Well(well)
+		if err != nil {
+			return nil, fmt.Errorf("matrix %q: %w", c.Name, err)
+		}
+		conc := conc
+		cell.Concentration = &conc
+	}
+	if c.LiquidClass != "" {
+		lc, err := LookupLiquidClass(c.LiquidClass)
+		if err != nil {
+			return nil, fmt.Errorf("matrix %q: %w", c.Name, err)
+		}
+		for _, row := range m.Cells {
+			for _, cell := range row {
+				cell.Liquid = lc
+			}
+		}
+	}
+	for well, name := range c.LiquidClasses {
+		cell, err := m.Well(well)
+		if err != nil {
+			return nil, fmt.Errorf("matrix %q: %w", c.Name, err)
+		}
+		if cell.Liquid, err = LookupLiquidClass(name); err != nil {
+			return nil, fmt.Errorf("matrix %q: %v: %w", c.Name, well, err)
+		}
+	}
+	return m, nil
+}
+
+func (c MatrixConfig) grid() (*Matrix, error) {
+	home := c.Home
+	kind := Unknown
+	if c.Kind != nil {
+		kind = *c.Kind
+	}
+	if c.Labware == "" {
+		if c.Rows <= 0 || c.Cols <= 0 {
+			return nil, fmt.Errorf("matrix %q: needs at least one row and column", c.Name)
+		}
+		m := NewMatrix(kind, c.Name, home, c.RowSpace, c.ColSpace, c.Rows, c.Cols)
+		m.SetDepth(c.Depth)
+		return m, nil
+	}
+	lw, err := LookupLabware(c.Labware)
+	if err != nil {
+		return nil, fmt.Errorf("matrix %q: %w", c.Name, err)
+	}
+	l := *lw
+	if c.Kind != nil {
+		l.Kind = kind
+	}
+	for _, f := range []struct {
+		dst *float32
+		src float32
+	}{{&l.RowSpace, c.RowSpace}, {&l.ColSpace, c.ColSpace}, {&l.Depth, c.Depth}} {
+		if f.src != 0 {
+			*f.dst = f.src
+		}
+	}
+	if c.Rows > 0 {
+		l.Rows = c.Rows
+	}
+	if c.Cols > 0 {
+		l.Cols = c.Cols
+	}
+	return l.At(c.Name, home), nil
+}
+
+// pipettes returns the names of the declared pipettes, or DefaultPipette.
+func (d *Deck) pipettes() []string {
+	if len(d.Pipettes) == 0 {
+		return []string{DefaultPipette}
+	}
+	res := make([]string, len(d.Pipettes))
+	for i, p := range d.Pipettes {
+		res[i] = p.Name
+	}
+	return res
+}
+
+// Pipette returns the pipette called name, or the first one declared if name
+// is empty. Settings a deck leaves out are taken from StockPipette; a deck
+// without pipettes has just the stock one.
+func (d *Deck) Pipette(name string) (*Pipette, error) {
+	if len(d.Pipettes) == 0 && (name == "" || name == DefaultPipette) {
+		return StockPipette(), nil
+	}
+	for _, p := range d.Pipettes {
+		if name != "" && p.Name != name {
+			continue
+		}
+		res := *p
+		stock := StockPipette()
+		if res.Curve.Slope == 0 && len(res.Curve.Points) == 0 {
+			res.Curve = stock.Curve
+		}
+		if res.AspirateRate == 0 {
+			res.AspirateRate = stock.AspirateRate
+		}
+		if res.DispenseRate == 0 {
+			res.DispenseRate = stock.DispenseRate
+		}
+		if res.MaxVolume == 0 {
+			res.MinVolume, res.MaxVolume = stock.MinVolume, stock.MaxVolume
+		}
+		if err := res.Validate(); err != nil {
+			return nil, err
+		}
+		return &res, nil
+	}
+	return nil, fmt.Errorf("no pipette named %q on the deck", name)
+}
+
+// Layout builds the matrices declared in d and validates them. Tip matrices
+// that do not name a pipette belong to the only one declared.
+func (d *Deck) Layout() (*Layout, error) {
+	for _, f := range d.Labware {
+		if !filepath.IsAbs(f) {
+			f = filepath.Join(d.dir, f)
+		}
+		if _, err := LoadLabware(f); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range d.LiquidClasses {
+		if err := c.Validate(); err != nil {
+			return nil, err
+		}
+		RegisterLiquidClass(c)
+	}
+	pipettes := d.pipettes()
+	if d.TravelMargin < 0 {
+		return nil, fmt.Errorf("travel margin cannot be negative")
+	}
+	if err := d.Speeds.Validate(); err != nil {
+		return nil, err
+	}
+	l := &Layout{Matrices: make([]*Matrix, len(d.Matrices)), TravelMargin: d.TravelMargin, Speeds: d.Speeds}
+	for i, c := range d.Matrices {
+		if c.Name == "" {
+			return nil, fmt.Errorf("matrix %v has no name", i+1)
+		}
+		switch c.Role {
+		case NoRole, Source, Destination, Tips:
+		default:
+			return nil, fmt.Errorf("matrix %q: unknown role %q", c.Name, c.Role)
+		}
+		m, err := c.build()
+		if err != nil {
+			return nil, err
+		}
+		if c.Role != NoRole {
+			m.Role = c.Role
+		}
+		m.Pipette = c.Pipette
+		if m.Kind == Tip && m.Pipette == "" && len(pipettes) == 1 {
+			m.Pipette = pipettes[0]
+		}
+		l.Matrices[i] = m
+	}
+	if err := l.Validate(pipettes...); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
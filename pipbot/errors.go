@@ -0,0 +1,109 @@
+package pipbot
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrOutOfTips is returned when a tip is needed and the tip box is empty.
+	ErrOutOfTips = errors.New("out of tips")
+	// ErrFirmware is returned when the printer reports an error it cannot
+	// recover from by resending the line.
+	ErrFirmware = errors.New("firmware error")
+	// ErrTimeout is returned when the printer stops acknowledging commands.
+	ErrTimeout = errors.New("timed out waiting for printer")
+	// ErrOutOfBounds is returned for moves outside the build volume and for
+	// cells that do not exist in their matrix.
+	ErrOutOfBounds = errors.New("out of bounds")
+	// ErrPlanParse is returned when a plan file cannot be read.
+	ErrPlanParse = errors.New("could not parse plan")
+	// ErrOverdraw is returned when more is drawn from a well than it holds.
+	ErrOverdraw = errors.New("not enough liquid")
+	// ErrOverflow is returned when a well would be filled past its capacity.
+	ErrOverflow = errors.New("well would overflow")
+	// ErrCollision is returned for moves that would run the tip into
+	// labware.
+	ErrCollision = errors.New("collision")
+)
+
+// FirmwareError is an error line reported by the printer.
+type FirmwareError struct {
+	Msg string
+}
+
+func (e *FirmwareError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrFirmware, e.Msg)
+}
+
+func (e *FirmwareError) Unwrap() error {
+	return ErrFirmware
+}
+
+// PlanError reports where a plan file could not be parsed. Line and Column
+// are 1-based; zero means unknown.
+type PlanError struct {
+	File   string
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *PlanError) Error() string {
+	switch {
+	case e.Line == 0:
+		return fmt.Sprintf("%v: %v", e.File, e.Err)
+	case e.Column == 0:
+		return fmt.Sprintf("%v:%v: %v", e.File, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%v:%v:%v: %v", e.File, e.Line, e.Column, e.Err)
+}
+
+func (e *PlanError) Is(target error) bool {
+	return target == ErrPlanParse
+}
+
+func (e *PlanError) Unwrap() error {
+	return e.Err
+}
+
+// Phase is the part of a transfer the bot is working on.
+type Phase uint8
+
+const (
+	Idle Phase = iota
+	PickingTip
+	Aspirating
+	Dispensing
+	Ejecting
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PickingTip:
+		return "picking up tip"
+	case Aspirating:
+		return "aspirating"
+	case Dispensing:
+		return "dispensing"
+	case Ejecting:
+		return "ejecting tip"
+	}
+	return "idle"
+}
+
+// TransferError is returned when a transfer fails part way through. The bot's
+// State at the time of the failure is available from PipBot.State.
+type TransferError struct {
+	Step  int
+	Phase Phase
+	Err   error
+}
+
+func (e *TransferError) Error() string {
+	return fmt.Sprintf("step %v: %v: %v", e.Step, e.Phase, e.Err)
+}
+
+func (e *TransferError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,126 @@
+package pipbot
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SourceVolume is how much a plan draws from one well.
+type SourceVolume struct {
+	// Source is the well, as "matrix:well".
+	Source string  `json:"source"`
+	Volume float32 `json:"volume"`
+}
+
+// Estimate is what a plan will take, worked out before it runs.
+type Estimate struct {
+	Transfers int `json:"transfers"`
+	// TipsNeeded is how many tips the plan picks up and TipsAvailable how
+	// many the tip racks hold now. RackSwaps is how many times the racks are
+	// replaced along the way.
+	TipsNeeded    int `json:"tips_needed"`
+	TipsAvailable int `json:"tips_available"`
+	RackSwaps     int `json:"rack_swaps"`
+	// Sources are the wells drawn from, in deck order.
+	Sources []SourceVolume `json:"sources"`
+	// Duration is how long the moves take at the deck's feed rates and
+	// accelerations. Waiting for racks to be replaced or for heaters is not
+	// counted.
+	Duration time.Duration `json:"duration"`
+}
+
+// Estimate checks actions as Run would and works out what running them will
+// take, from where the bot is now. The time comes from running their G-code
+// on a Simulator.
+func (b *PipBot) Estimate(actions []Action) (*Estimate, error) {
+	if err := b.check(actions); err != nil {
+		return nil, err
+	}
+	res := &Estimate{}
+	drawn := make(map[*Cell]float32)
+	for _, a := range actions {
+		switch a := a.(type) {
+		case *Transfer:
+			res.Transfers++
+			if a.Tip != nil {
+				res.TipsNeeded++
+			}
+			if a.src != nil {
+				drawn[a.src] += a.Volume
+			}
+		case *ReplaceTips:
+			res.RackSwaps++
+		}
+	}
+	for _, m := range b.racks {
+		if b.Tips != nil {
+			res.TipsAvailable += b.Tips.Left(m)
+		} else {
+			res.TipsAvailable += m.Rows * m.Columns
+		}
+	}
+	if b.Layout != nil {
+		for _, m := range b.Layout.Matrices {
+			for row, cells := range m.Cells {
+				for col, c := range cells {
+					if v, ok := drawn[c]; ok {
+						res.Sources = append(res.Sources, SourceVolume{Source: m.Name + ":" + WellName(row, col), Volume: hundredths(v)})
+					}
+				}
+			}
+		}
+	}
+
+	// the printer is as Init leaves it: homed, with the plunger free to move
+	// cold and in absolute mode
+	s := NewSimulator(b.pipette())
+	s.homed, s.coldExtrude, s.accel = true, true, b.speeds().Acceleration
+	if b.homed {
+		s.at = b.Commanded
+	}
+	for _, a := range actions {
+		for _, l := range a.Bytes() {
+			if _, err := s.Write(l); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := s.Close(); err != nil {
+		return nil, err
+	}
+	res.Duration = s.Result().Duration
+	return res, nil
+}
+
+// EstimateRecipe plans r and estimates running it. Like PlanRecipe it needs
+// the bot to have been through Prepare or Init.
+func (b *PipBot) EstimateRecipe(r *Recipe) (*Estimate, error) {
+	actions, err := b.PlanRecipe(r)
+	if err != nil {
+		return nil, err
+	}
+	return b.Estimate(actions)
+}
+
+// WriteSummary writes e for someone about to start a run.
+func (e *Estimate) WriteSummary(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "transfers: %v\n", e.Transfers)
+	fmt.Fprintf(bw, "tips:      %v needed, %v available", e.TipsNeeded, e.TipsAvailable)
+	switch e.RackSwaps {
+	case 0:
+		fmt.Fprintln(bw)
+	case 1:
+		fmt.Fprintln(bw, ", racks replaced once")
+	default:
+		fmt.Fprintf(bw, ", racks replaced %v times\n", e.RackSwaps)
+	}
+	fmt.Fprintln(bw, "drawn:")
+	for _, s := range e.Sources {
+		fmt.Fprintf(bw, "  %v: %v µL\n", s.Source, num(s.Volume))
+	}
+	fmt.Fprintf(bw, "duration:  %v\n", e.Duration.Round(time.Second))
+	return bw.Flush()
+}
@@ -0,0 +1,39 @@
+package pipbot
+
+import (
+	"reflect"
+	"testing"
+
+	"pipbot/graph/model"
+)
+
+// TestEstimateStoredRecipe estimates a recipe on its own grids without the
+// printer moving.
+func TestEstimateStoredRecipe(t *testing.T) {
+	sim := NewSimulator(nil)
+	b := NewPipBotOn(sim)
+	b.Layout = b.Layout.WithGrids([]*model.Grid{
+		{Name: "src", Home: &model.Position{X: 40, Y: 170, Z: 75}, RowSpace: 20, ColSpace: 20, NRows: 2, NCols: 2},
+		{Name: "plate", Home: &model.Position{X: 30, Y: 80, Z: 74.5}, RowSpace: 9, ColSpace: 9, NRows: 8, NCols: 12},
+	})
+	if err := b.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	est, err := b.EstimateRecipe(&Recipe{Transfers: []*model.Transfer{
+		{Source: &model.Node{Grid: "src", Position: "A1"}, Dest: &model.Node{Grid: "plate", Position: "B2"}, Volume: 20},
+		{Source: &model.Node{Grid: "src", Position: "B2"}, Dest: &model.Node{Grid: "plate", Position: "B3"}, Volume: 30},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SourceVolume{{Source: "src:A1", Volume: 20}, {Source: "src:B2", Volume: 30}}
+	if !reflect.DeepEqual(est.Sources, want) {
+		t.Errorf("sources %v, want %v", est.Sources, want)
+	}
+	if est.Transfers != 2 || est.TipsAvailable != 96 || est.Duration <= 0 {
+		t.Errorf("estimate %+v", est)
+	}
+	if n := len(sim.Result().Timeline); n != 0 {
+		t.Errorf("%v lines sent to the printer", n)
+	}
+}
@@ -0,0 +1,128 @@
+package pipbot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeFirmware answers numbered, checksummed lines the way Marlin does. Plug
+// Reply into a FakePrinter to script acknowledgements, resend requests and
+// busy keepalives without a printer attached. Like Marlin it echoes the text
+// of M118.
+type FakeFirmware struct {
+	// Corrupt lists line numbers whose first delivery is rejected with a
+	// checksum mismatch, forcing a resend.
+	Corrupt map[int]bool
+	// Busy is how many "busy: processing" keepalives are sent before the ok
+	// for commands starting with the key, e.g. "G28".
+	Busy map[string]int
+	// Silent lists commands that are swallowed without any reply at all.
+	Silent map[string]bool
+	// Slow is how long commands starting with the key take to run before
+	// their ok is sent.
+	Slow map[string]time.Duration
+	// Reports is how many temperature reports are printed before the ok for
+	// commands starting with the key, as M109 and M190 do on firmware built
+	// without BUSY_WHILE_HEATING.
+	Reports  map[string]int
+	mu       sync.Mutex
+	last     int
+	received []string
+}
+
+// Received returns the commands run so far, in order, without line numbers
+// or checksums. Resent lines are only counted once, and slow ones once they
+// are done.
+func (f *FakeFirmware) Received() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.received...)
+}
+
+func (f *FakeFirmware) resend(msg string) []string {
+	return []string{
+		fmt.Sprintf("Error:%v, Last Line: %v", msg, f.last),
+		fmt.Sprintf("Resend: %v", f.last+1),
+		"ok",
+	}
+}
+
+// Reply returns Marlin's response to a single line.
+func (f *FakeFirmware) Reply(line string) []string {
+	cmd, res := f.accept(line)
+	if res != nil {
+		return res
+	}
+	for prefix, d := range f.Slow {
+		if strings.HasPrefix(cmd, prefix) {
+			time.Sleep(d)
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.received = append(f.received, cmd)
+	for prefix, n := range f.Busy {
+		if strings.HasPrefix(cmd, prefix) {
+			for i := 0; i < n; i++ {
+				res = append(res, "echo:busy: processing")
+			}
+		}
+	}
+	for prefix, n := range f.Reports {
+		if strings.HasPrefix(cmd, prefix) {
+			for i := 0; i < n; i++ {
+				res = append(res, "T:25.00 /200.00 B:25.00 /0.00 @:127 B@:0")
+			}
+		}
+	}
+	if strings.HasPrefix(cmd, "M118 ") {
+		res = append(res, strings.TrimPrefix(cmd, "M118 "))
+	}
+	for prefix := range f.Silent {
+		if strings.HasPrefix(cmd, prefix) {
+			return res
+		}
+	}
+	return append(res, "ok")
+}
+
+// accept checks the line number and checksum of line and returns the command
+// in it, or the reply asking for it again.
+func (f *FakeFirmware) accept(line string) (string, []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cmd := line
+	if strings.HasPrefix(line, "N") {
+		star := strings.LastIndexByte(line, '*')
+		if star < 0 {
+			return "", f.resend("No Checksum with line number")
+		}
+		cs, err := strconv.Atoi(line[star+1:])
+		if err != nil || byte(cs) != checksum(line[:star]) {
+			return "", f.resend("checksum mismatch")
+		}
+		num, rest, _ := strings.Cut(line[:star], " ")
+		n, err := strconv.Atoi(num[1:])
+		if err != nil {
+			return "", f.resend("Line Number is not Last Line Number+1")
+		}
+		cmd = rest
+		if f.Corrupt[n] {
+			delete(f.Corrupt, n)
+			return "", f.resend("checksum mismatch")
+		}
+		if !strings.HasPrefix(cmd, "M110") && n != f.last+1 {
+			return "", f.resend("Line Number is not Last Line Number+1")
+		}
+		f.last = n
+	}
+	if strings.HasPrefix(cmd, "M110") {
+		if i := strings.Index(cmd, " N"); i >= 0 {
+			f.last, _ = strconv.Atoi(cmd[i+2:])
+		}
+	}
+	return cmd, nil
+}
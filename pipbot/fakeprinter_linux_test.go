@@ -0,0 +1,111 @@
+//go:build linux
+
+package pipbot
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// FakePrinter is a pseudo-terminal standing in for a printer. Pass Port() to
+// NewSerial or NewPipBot and the bot talks to it exactly as it would to a real
+// board, which makes the serial path testable with nothing plugged in.
+type FakePrinter struct {
+	// Reply returns the lines the fake answers with for each line it
+	// receives. When nil every line is answered with "ok".
+	Reply func(line string) []string
+	// Delay is how long the printer takes over each line it answers with.
+	Delay  time.Duration
+	master *os.File
+	slave  *os.File
+	mu     sync.Mutex
+	lines  []string
+	done   chan struct{}
+}
+
+// NewFakePrinter allocates a pty pair and starts answering on the master side.
+func NewFakePrinter() (*FakePrinter, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	var n int
+	err = control(master, func(fd int) error {
+		if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+			return err
+		}
+		var err error
+		n, err = unix.IoctlGetInt(fd, unix.TIOCGPTN)
+		return err
+	})
+	if err != nil {
+		_ = master.Close()
+		return nil, err
+	}
+	// hold the slave open so the master never sees a hangup between clients
+	slave, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		_ = master.Close()
+		return nil, err
+	}
+	if err = makeRaw(slave, unix.B115200); err != nil {
+		_ = slave.Close()
+		_ = master.Close()
+		return nil, err
+	}
+	f := &FakePrinter{
+		master: master,
+		slave:  slave,
+		done:   make(chan struct{}),
+	}
+	go f.serve()
+	return f, nil
+}
+
+// Port is the device path of the printer side of the pty.
+func (f *FakePrinter) Port() string {
+	return f.slave.Name()
+}
+
+// Lines returns every line received so far, without line endings.
+func (f *FakePrinter) Lines() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.lines...)
+}
+
+// Close releases both ends of the pty and waits for the reply loop to exit.
+func (f *FakePrinter) Close() error {
+	err := f.master.Close()
+	_ = f.slave.Close()
+	<-f.done
+	return err
+}
+
+func (f *FakePrinter) serve() {
+	defer close(f.done)
+	scan := bufio.NewScanner(f.master)
+	for scan.Scan() {
+		line := strings.TrimRight(scan.Text(), "\r")
+		f.mu.Lock()
+		f.lines = append(f.lines, line)
+		reply, delay := f.Reply, f.Delay
+		f.mu.Unlock()
+		res := []string{"ok"}
+		if reply != nil {
+			res = reply(line)
+		}
+		for _, r := range res {
+			time.Sleep(delay)
+			if _, err := f.master.WriteString(r + "\n"); err != nil {
+				return
+			}
+		}
+	}
+}
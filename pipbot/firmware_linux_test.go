@@ -0,0 +1,121 @@
+//go:build linux
+
+package pipbot
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// marlinOn connects a Marlin transport to a fake printer running fw.
+func marlinOn(t *testing.T, fw *FakeFirmware) (*Marlin, *FakePrinter) {
+	t.Helper()
+	f, err := NewFakePrinter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Reply = fw.Reply
+	s, err := NewSerial(f.Port(), Baud)
+	if err != nil {
+		f.Close()
+		t.Fatal(err)
+	}
+	m := NewMarlin(s)
+	m.Timeout = 2 * time.Second
+	t.Cleanup(func() {
+		m.Close()
+		f.Close()
+	})
+	return m, f
+}
+
+func TestMarlinFlowControl(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		fw   *FakeFirmware
+		// sent is how many framed lines the printer should see, counting
+		// resends and the M110 reset
+		sent int
+	}{
+		{name: "ok", fw: &FakeFirmware{}, sent: 4},
+		{name: "resend", fw: &FakeFirmware{Corrupt: map[int]bool{2: true}}, sent: 5},
+		{name: "busy", fw: &FakeFirmware{Busy: map[string]int{"G28": 3}}, sent: 4},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m, f := marlinOn(t, tc.fw)
+			if _, err := m.Write([]byte("G28\nG0 X10 ; to the side\n\nM400\n")); err != nil {
+				t.Fatal(err)
+			}
+			want := []string{"M110 N0", "G28", "G0 X10", "M400"}
+			if got := tc.fw.Received(); !reflect.DeepEqual(got, want) {
+				t.Errorf("firmware accepted %q, want %q", got, want)
+			}
+			if got := len(f.Lines()); got != tc.sent {
+				t.Errorf("printer saw %v lines, want %v: %q", got, tc.sent, f.Lines())
+			}
+		})
+	}
+}
+
+func TestMarlinTimeout(t *testing.T) {
+	fw := &FakeFirmware{Silent: map[string]bool{"G4": true}}
+	m, _ := marlinOn(t, fw)
+	m.Timeout = 200 * time.Millisecond
+	if _, err := m.Write([]byte("G4 S1\n")); err == nil {
+		t.Fatal("a silent firmware should time out")
+	}
+}
+
+func TestFakeFirmwareChecksum(t *testing.T) {
+	fw := &FakeFirmware{}
+	if got := fw.Reply(strings.TrimSuffix(frame(1, "G28"), "\n")); !reflect.DeepEqual(got, []string{"ok"}) {
+		t.Fatalf("good line got %q", got)
+	}
+	got := fw.Reply("N2 G0 X1*0")
+	want := []string{"Error:checksum mismatch, Last Line: 1", "Resend: 2", "ok"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("bad checksum got %q, want %q", got, want)
+	}
+	if r, _ := parseReply(got[1]); r.kind != replyResend || r.line != 2 {
+		t.Errorf("%q parsed as %+v", got[1], r)
+	}
+	if got := fw.Received(); !reflect.DeepEqual(got, []string{"G28"}) {
+		t.Errorf("firmware accepted %q", got)
+	}
+}
+
+// TestMarlinLateOk has the ok for a line arrive after it timed out. It must
+// not be taken for the ok of the next line, or the sender runs ahead.
+func TestMarlinLateOk(t *testing.T) {
+	fw := &FakeFirmware{Slow: map[string]time.Duration{"G4": 450 * time.Millisecond, "M400": 200 * time.Millisecond}}
+	m, _ := marlinOn(t, fw)
+	m.Timeout = 300 * time.Millisecond
+	if _, err := m.Write([]byte("G4 P450\n")); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("got %v, want a timeout", err)
+	}
+	if _, err := m.Write([]byte("G0 X1\nM400\n")); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"M110 N0", "G4 P450", "M110 N0", "M118 pipbot sync 1", "G0 X1", "M400"}
+	if got := fw.Received(); !reflect.DeepEqual(got, want) {
+		t.Errorf("firmware had run %q when the write returned, want %q", got, want)
+	}
+}
+
+// TestMarlinHeatReports waits out a heat that only prints temperature
+// reports, for longer than the timeout.
+func TestMarlinHeatReports(t *testing.T) {
+	fw := &FakeFirmware{Reports: map[string]int{"M109": 6}}
+	m, f := marlinOn(t, fw)
+	f.Delay = 100 * time.Millisecond
+	m.Timeout = 300 * time.Millisecond
+	if _, err := m.Write([]byte("M109 S200\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fw.Received(), []string{"M110 N0", "M109 S200"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("firmware accepted %q, want %q", got, want)
+	}
+}
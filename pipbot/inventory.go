@@ -0,0 +1,182 @@
+package pipbot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TipInventory is which tips have been taken from each tip rack. Kept in a
+// file, it lets a part-used rack carry on where the last run left it.
+type TipInventory struct {
+	// Used lists the wells taken from each rack, by matrix name.
+	Used map[string][]string `yaml:"used" json:"used"`
+	file string
+}
+
+// LoadTips reads the inventory kept in file. A file that does not exist yet
+// is an inventory of full racks. Files ending in .json are JSON, anything
+// else YAML.
+func LoadTips(file string) (*TipInventory, error) {
+	inv := &TipInventory{file: file}
+	b, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return inv, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		err = json.Unmarshal(b, inv)
+	} else {
+		err = yaml.Unmarshal(b, inv)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", file, err)
+	}
+	return inv, nil
+}
+
+// Save writes the inventory back to the file it was loaded from. Inventories
+// that were not loaded from a file are not saved.
+func (inv *TipInventory) Save() error {
+	if inv.file == "" {
+		return nil
+	}
+	for _, wells := range inv.Used {
+		sortWells(wells)
+	}
+	var b []byte
+	var err error
+	if strings.EqualFold(filepath.Ext(inv.file), ".json") {
+		if b, err = json.MarshalIndent(inv, "", "  "); err == nil {
+			b = append(b, '\n')
+		}
+	} else {
+		b, err = yaml.Marshal(inv)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(inv.file, b, 0o644)
+}
+
+// sortWells puts wells in the order tips are taken: along each row in turn.
+func sortWells(wells []string) {
+	sort.Slice(wells, func(i, j int) bool {
+		ri, ci, _ := ParseWell(wells[i])
+		rj, cj, _ := ParseWell(wells[j])
+		return ri < rj || ri == rj && ci < cj
+	})
+}
+
+func (inv *TipInventory) used(rack, well string) bool {
+	for _, w := range inv.Used[rack] {
+		if w == well {
+			return true
+		}
+	}
+	return false
+}
+
+// Use marks the tip in well of rack as taken.
+func (inv *TipInventory) Use(rack, well string) {
+	if inv.used(rack, well) {
+		return
+	}
+	if inv.Used == nil {
+		inv.Used = make(map[string][]string)
+	}
+	inv.Used[rack] = append(inv.Used[rack], well)
+}
+
+// Reset marks racks as full.
+func (inv *TipInventory) Reset(racks ...string) {
+	for _, r := range racks {
+		delete(inv.Used, r)
+	}
+}
+
+// Mark records that the first n tips of m have been taken and the rest are
+// still there, for a rack that was put on the deck part used.
+func (inv *TipInventory) Mark(m *Matrix, n int) error {
+	if n < 0 || n > m.Rows*m.Columns {
+		return fmt.Errorf("%v holds %v tips, cannot mark %v used", m.Name, m.Rows*m.Columns, n)
+	}
+	inv.Reset(m.Name)
+	for i := 0; i < n; i++ {
+		inv.Use(m.Name, WellName(i/m.Columns, i%m.Columns))
+	}
+	return nil
+}
+
+// Left is the number of tips still in m.
+func (inv *TipInventory) Left(m *Matrix) int {
+	n := m.Rows * m.Columns
+	for _, w := range inv.Used[m.Name] {
+		if row, col, err := ParseWell(w); err == nil && row < m.Rows && col < m.Columns {
+			n--
+		}
+	}
+	return n
+}
+
+// next finds the first tip left in racks, taking them in order.
+func (inv *TipInventory) next(racks []*Matrix) (*Matrix, string, bool) {
+	for _, m := range racks {
+		for row := 0; row < m.Rows; row++ {
+			for col := 0; col < m.Columns; col++ {
+				if w := WellName(row, col); !inv.used(m.Name, w) {
+					return m, w, true
+				}
+			}
+		}
+	}
+	return nil, "", false
+}
+
+// Copy returns a copy of inv that is not saved to its file, for dry runs.
+func (inv *TipInventory) Copy() *TipInventory {
+	return inv.clone()
+}
+
+func (inv *TipInventory) clone() *TipInventory {
+	res := &TipInventory{Used: make(map[string][]string, len(inv.Used))}
+	for k, v := range inv.Used {
+		res.Used[k] = append([]string(nil), v...)
+	}
+	return res
+}
+
+// tipRef is where a tip was taken from.
+type tipRef struct {
+	rack, well string
+}
+
+// ReplaceTips stops the run for the tip racks to be swapped for full ones.
+// The bot asks through its Prompt when it has one; otherwise the printer waits
+// for its button.
+type ReplaceTips struct {
+	Racks []string
+}
+
+func (r *ReplaceTips) message() string {
+	if len(r.Racks) == 1 {
+		return fmt.Sprintf("Replace tip rack %v", r.Racks[0])
+	}
+	return fmt.Sprintf("Replace tip racks %v", strings.Join(r.Racks, ", "))
+}
+
+func (r *ReplaceTips) Bytes() [][]byte {
+	return [][]byte{[]byte("M400\n"), []byte("M0 " + r.message() + "\n")}
+}
+
+func (r *ReplaceTips) Finish() {
+
+}
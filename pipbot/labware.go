@@ -0,0 +1,248 @@
+package pipbot
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Bottom is the shape of the bottom of a well.
+type Bottom uint8
+
+const (
+	FlatBottom Bottom = iota
+	RoundBottom
+	VBottom
+)
+
+var bottomNames = map[Bottom]string{
+	FlatBottom:  "flat",
+	RoundBottom: "u",
+	VBottom:     "v",
+}
+
+func (b Bottom) String() string {
+	if n, ok := bottomNames[b]; ok {
+		return n
+	}
+	return fmt.Sprintf("Bottom(%d)", b)
+}
+
+func (b Bottom) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+func (b *Bottom) UnmarshalText(text []byte) error {
+	for k, n := range bottomNames {
+		if strings.EqualFold(n, string(text)) {
+			*b = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown well bottom %q", text)
+}
+
+// Labware is the geometry of a plate, tube rack or tip box. Lengths are in mm
+// and volumes in µL. Round wells have a Diameter, square ones XSize and YSize.
+type Labware struct {
+	Name      string   `json:"name"`
+	Kind      CellType `json:"kind"`
+	Rows      int      `json:"rows"`
+	Cols      int      `json:"cols"`
+	RowSpace  float32  `json:"row_space"`
+	ColSpace  float32  `json:"col_space"`
+	Depth     float32  `json:"depth"`
+	Diameter  float32  `json:"diameter,omitempty"`
+	XSize     float32  `json:"x_size,omitempty"`
+	YSize     float32  `json:"y_size,omitempty"`
+	Bottom    Bottom   `json:"bottom"`
+	MaxVolume float32  `json:"max_volume"`
+	// Height is how far the top of the labware stands above the bed.
+	Height float32 `json:"height"`
+}
+
+// At places l on the deck as a Matrix called name with its A1 well at home.
+func (l *Labware) At(name string, home Position) *Matrix {
+	m := NewMatrix(l.Kind, name, home, l.RowSpace, l.ColSpace, l.Rows, l.Cols)
+	m.SetDepth(l.Depth)
+	m.Labware = l
+	m.SetMaxVolume(l.MaxVolume)
+	m.SetWell(l.well())
+	return m
+}
+
+var (
+	labwareMu sync.RWMutex
+	labware   = map[string]*Labware{}
+)
+
+// RegisterLabware adds l to the library, replacing any definition with the
+// same name.
+func RegisterLabware(l *Labware) {
+	labwareMu.Lock()
+	defer labwareMu.Unlock()
+	labware[l.Name] = l
+}
+
+// LookupLabware returns the labware definition called name.
+func LookupLabware(name string) (*Labware, error) {
+	labwareMu.RLock()
+	defer labwareMu.RUnlock()
+	l, ok := labware[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown labware %q", name)
+	}
+	return l, nil
+}
+
+// LabwareNames lists the library in alphabetical order.
+func LabwareNames() []string {
+	labwareMu.RLock()
+	defer labwareMu.RUnlock()
+	res := make([]string, 0, len(labware))
+	for n := range labware {
+		res = append(res, n)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// otWell is a well in an Opentrons labware definition. Coordinates are the
+// center of the well bottom, measured from the front left corner of the
+// labware.
+type otWell struct {
+	Depth             float32 `json:"depth"`
+	TotalLiquidVolume float32 `json:"totalLiquidVolume"`
+	Shape             string  `json:"shape"`
+	Diameter          float32 `json:"diameter"`
+	XDimension        float32 `json:"xDimension"`
+	YDimension        float32 `json:"yDimension"`
+	X                 float32 `json:"x"`
+	Y                 float32 `json:"y"`
+	Z                 float32 `json:"z"`
+}
+
+// otLabware is the part of the Opentrons labware schema (v2) PipBot uses.
+type otLabware struct {
+	Ordering [][]string `json:"ordering"`
+	Metadata struct {
+		DisplayCategory string `json:"displayCategory"`
+	} `json:"metadata"`
+	Dimensions struct {
+		ZDimension float32 `json:"zDimension"`
+	} `json:"dimensions"`
+	Wells  map[string]otWell `json:"wells"`
+	Groups []struct {
+		Metadata struct {
+			WellBottomShape string `json:"wellBottomShape"`
+		} `json:"metadata"`
+	} `json:"groups"`
+	Parameters struct {
+		LoadName  string `json:"loadName"`
+		IsTipRack bool   `json:"isTipRack"`
+	} `json:"parameters"`
+}
+
+// ParseOpentrons reads an Opentrons labware definition.
+func ParseOpentrons(b []byte) (*Labware, error) {
+	ot := otLabware{}
+	if err := json.Unmarshal(b, &ot); err != nil {
+		return nil, err
+	}
+	if len(ot.Ordering) == 0 || len(ot.Ordering[0]) == 0 {
+		return nil, fmt.Errorf("labware %q has no wells", ot.Parameters.LoadName)
+	}
+	cols, rows := len(ot.Ordering), len(ot.Ordering[0])
+	a1, ok := ot.Wells[ot.Ordering[0][0]]
+	if !ok {
+		return nil, fmt.Errorf("labware %q: well %v is not defined", ot.Parameters.LoadName, ot.Ordering[0][0])
+	}
+	l := &Labware{
+		Name:      ot.Parameters.LoadName,
+		Kind:      Standard,
+		Rows:      rows,
+		Cols:      cols,
+		Depth:     a1.Depth,
+		MaxVolume: a1.TotalLiquidVolume,
+		Height:    ot.Dimensions.ZDimension,
+	}
+	if a1.Shape == "rectangular" {
+		l.XSize, l.YSize = a1.XDimension, a1.YDimension
+	} else {
+		l.Diameter = a1.Diameter
+	}
+	// rows run from the back of the labware to the front
+	if rows > 1 {
+		l.RowSpace = hundredths(a1.Y - ot.Wells[ot.Ordering[0][1]].Y)
+	}
+	if cols > 1 {
+		l.ColSpace = hundredths(ot.Wells[ot.Ordering[1][0]].X - a1.X)
+	}
+	switch {
+	case ot.Parameters.IsTipRack:
+		l.Kind = Tip
+	case ot.Metadata.DisplayCategory == "tubeRack", ot.Metadata.DisplayCategory == "reservoir":
+		l.Kind = Stock
+	}
+	if len(ot.Groups) > 0 && ot.Groups[0].Metadata.WellBottomShape != "" {
+		if err := l.Bottom.UnmarshalText([]byte(ot.Groups[0].Metadata.WellBottomShape)); err != nil {
+			return nil, fmt.Errorf("labware %q: %w", l.Name, err)
+		}
+	}
+	return l, nil
+}
+
+// hundredths rounds v to 0.01 mm, hiding float noise in differences.
+func hundredths(v float32) float32 {
+	return float32(math.Round(float64(v)*100) / 100)
+}
+
+// LoadLabware reads an Opentrons labware definition file and adds it to the
+// library.
+func LoadLabware(file string) (*Labware, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	l, err := ParseOpentrons(b)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", file, err)
+	}
+	RegisterLabware(l)
+	return l, nil
+}
+
+func init() {
+	for _, l := range []*Labware{
+		{Name: "corning_6_wellplate_16.8ml_flat", Kind: Standard, Rows: 2, Cols: 3, RowSpace: 39.12, ColSpace: 39.12,
+			Depth: 17.4, Diameter: 35.43, Bottom: FlatBottom, MaxVolume: 16800, Height: 20.02},
+		{Name: "corning_12_wellplate_6.9ml_flat", Kind: Standard, Rows: 3, Cols: 4, RowSpace: 26.01, ColSpace: 26.01,
+			Depth: 17.4, Diameter: 22.73, Bottom: FlatBottom, MaxVolume: 6900, Height: 20.02},
+		{Name: "corning_24_wellplate_3.4ml_flat", Kind: Standard, Rows: 4, Cols: 6, RowSpace: 19.3, ColSpace: 19.3,
+			Depth: 17.4, Diameter: 16.26, Bottom: FlatBottom, MaxVolume: 3400, Height: 20.02},
+		{Name: "corning_48_wellplate_1.6ml_flat", Kind: Standard, Rows: 6, Cols: 8, RowSpace: 13.08, ColSpace: 13.08,
+			Depth: 17.4, Diameter: 11.56, Bottom: FlatBottom, MaxVolume: 1600, Height: 20.02},
+		{Name: "corning_96_wellplate_360ul_flat", Kind: Standard, Rows: 8, Cols: 12, RowSpace: 9, ColSpace: 9,
+			Depth: 10.67, Diameter: 6.86, Bottom: FlatBottom, MaxVolume: 360, Height: 14.22},
+		{Name: "corning_384_wellplate_112ul_flat", Kind: Standard, Rows: 16, Cols: 24, RowSpace: 4.5, ColSpace: 4.5,
+			Depth: 11.56, XSize: 3.63, YSize: 3.63, Bottom: FlatBottom, MaxVolume: 112, Height: 14.22},
+		{Name: "opentrons_96_tiprack_300ul", Kind: Tip, Rows: 8, Cols: 12, RowSpace: 9, ColSpace: 9,
+			Depth: 59.3, Diameter: 5.23, Bottom: VBottom, MaxVolume: 300, Height: 64.49},
+		{Name: "opentrons_96_filtertiprack_200ul", Kind: Tip, Rows: 8, Cols: 12, RowSpace: 9, ColSpace: 9,
+			Depth: 59.3, Diameter: 5.23, Bottom: VBottom, MaxVolume: 200, Height: 64.49},
+		{Name: "opentrons_96_tiprack_1000ul", Kind: Tip, Rows: 8, Cols: 12, RowSpace: 9, ColSpace: 9,
+			Depth: 88, Diameter: 7.62, Bottom: VBottom, MaxVolume: 1000, Height: 97.47},
+		{Name: "opentrons_24_tuberack_eppendorf_1.5ml_safelock_snapcap", Kind: Stock, Rows: 4, Cols: 6, RowSpace: 19.28, ColSpace: 19.28,
+			Depth: 37.9, Diameter: 8.7, Bottom: VBottom, MaxVolume: 1500, Height: 79.85},
+		{Name: "opentrons_15_tuberack_falcon_15ml_conical", Kind: Stock, Rows: 3, Cols: 5, RowSpace: 25, ColSpace: 25,
+			Depth: 117.98, Diameter: 14.9, Bottom: VBottom, MaxVolume: 15000, Height: 124.35},
+		{Name: "opentrons_6_tuberack_falcon_50ml_conical", Kind: Stock, Rows: 2, Cols: 3, RowSpace: 35, ColSpace: 35,
+			Depth: 112.85, Diameter: 28.14, Bottom: VBottom, MaxVolume: 50000, Height: 124.35},
+	} {
+		RegisterLabware(l)
+	}
+}
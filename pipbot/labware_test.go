@@ -0,0 +1,115 @@
+package pipbot
+
+import (
+	"reflect"
+	"testing"
+)
+
+// a trimmed Opentrons v2 definition of a 2x3 tube rack: columns in ordering,
+// rows running from the back (high Y) to the front
+const otTubeRack = `{
+  "ordering": [["A1", "B1"], ["A2", "B2"], ["A3", "B3"]],
+  "metadata": {"displayCategory": "tubeRack"},
+  "dimensions": {"zDimension": 124.35},
+  "wells": {
+    "A1": {"depth": 112.85, "totalLiquidVolume": 50000, "shape": "circular", "diameter": 28.14, "x": 35.5, "y": 60.6, "z": 6.7},
+    "B1": {"depth": 112.85, "totalLiquidVolume": 50000, "shape": "circular", "diameter": 28.14, "x": 35.5, "y": 25.6, "z": 6.7},
+    "A2": {"depth": 112.85, "totalLiquidVolume": 50000, "shape": "circular", "diameter": 28.14, "x": 70.5, "y": 60.6, "z": 6.7},
+    "B2": {"depth": 112.85, "totalLiquidVolume": 50000, "shape": "circular", "diameter": 28.14, "x": 70.5, "y": 25.6, "z": 6.7},
+    "A3": {"depth": 112.85, "totalLiquidVolume": 50000, "shape": "circular", "diameter": 28.14, "x": 105.5, "y": 60.6, "z": 6.7},
+    "B3": {"depth": 112.85, "totalLiquidVolume": 50000, "shape": "circular", "diameter": 28.14, "x": 105.5, "y": 25.6, "z": 6.7}
+  },
+  "groups": [{"metadata": {"wellBottomShape": "v"}}],
+  "parameters": {"loadName": "test_6_tuberack_50ml", "isTipRack": false}
+}`
+
+// a trimmed definition of a reservoir-like plate with square wells in one row
+const otSquarePlate = `{
+  "ordering": [["A1"], ["A2"]],
+  "metadata": {"displayCategory": "wellPlate"},
+  "dimensions": {"zDimension": 14.22},
+  "wells": {
+    "A1": {"depth": 11.56, "totalLiquidVolume": 112, "shape": "rectangular", "xDimension": 3.63, "yDimension": 3.63, "x": 12.12, "y": 80.76, "z": 2.79},
+    "A2": {"depth": 11.56, "totalLiquidVolume": 112, "shape": "rectangular", "xDimension": 3.63, "yDimension": 3.63, "x": 16.62, "y": 80.76, "z": 2.79}
+  },
+  "groups": [{"metadata": {"wellBottomShape": "flat"}}],
+  "parameters": {"loadName": "test_2_wellplate_112ul"}
+}`
+
+// a trimmed tip rack, which has no bottom shape of its own
+const otTipRack = `{
+  "ordering": [["A1", "B1"]],
+  "metadata": {"displayCategory": "tipRack"},
+  "dimensions": {"zDimension": 64.49},
+  "wells": {
+    "A1": {"depth": 59.3, "totalLiquidVolume": 200, "shape": "circular", "diameter": 5.23, "x": 14.38, "y": 74.24, "z": 5.39},
+    "B1": {"depth": 59.3, "totalLiquidVolume": 200, "shape": "circular", "diameter": 5.23, "x": 14.38, "y": 65.24, "z": 5.39}
+  },
+  "groups": [{"metadata": {}}],
+  "parameters": {"loadName": "test_2_tiprack_200ul", "isTipRack": true}
+}`
+
+func TestParseOpentrons(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		def  string
+		want Labware
+	}{
+		{name: "tube rack", def: otTubeRack, want: Labware{
+			Name: "test_6_tuberack_50ml", Kind: Stock, Rows: 2, Cols: 3, RowSpace: 35, ColSpace: 35,
+			Depth: 112.85, Diameter: 28.14, Bottom: VBottom, MaxVolume: 50000, Height: 124.35,
+		}},
+		{name: "square wells", def: otSquarePlate, want: Labware{
+			Name: "test_2_wellplate_112ul", Kind: Standard, Rows: 1, Cols: 2, ColSpace: 4.5,
+			Depth: 11.56, XSize: 3.63, YSize: 3.63, Bottom: FlatBottom, MaxVolume: 112, Height: 14.22,
+		}},
+		{name: "tip rack", def: otTipRack, want: Labware{
+			Name: "test_2_tiprack_200ul", Kind: Tip, Rows: 2, Cols: 1, RowSpace: 9,
+			Depth: 59.3, Diameter: 5.23, MaxVolume: 200, Height: 64.49,
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseOpentrons([]byte(tc.def))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*got, tc.want) {
+				t.Errorf("got %+v\nwant %+v", *got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseOpentronsErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name, def string
+	}{
+		{name: "no wells", def: `{"ordering": [], "parameters": {"loadName": "empty"}}`},
+		{name: "undefined well", def: `{"ordering": [["A1"]], "wells": {}, "parameters": {"loadName": "missing"}}`},
+		{name: "unknown bottom", def: `{"ordering": [["A1"]], "wells": {"A1": {}}, "groups": [{"metadata": {"wellBottomShape": "w"}}]}`},
+		{name: "not json", def: `ordering`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseOpentrons([]byte(tc.def)); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
+
+func TestTipRackPresets(t *testing.T) {
+	for name, volume := range map[string]float32{
+		"opentrons_96_filtertiprack_200ul": 200,
+		"opentrons_96_tiprack_300ul":       300,
+		"opentrons_96_tiprack_1000ul":      1000,
+	} {
+		l, err := LookupLabware(name)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if l.Kind != Tip || l.MaxVolume != volume || l.Rows*l.Cols != 96 {
+			t.Errorf("%v: %+v", name, l)
+		}
+	}
+}
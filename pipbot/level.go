@@ -0,0 +1,141 @@
+package pipbot
+
+import "math"
+
+const (
+	// DefaultImmersion is how far below the liquid surface the tip aspirates
+	// when the liquid class does not say.
+	DefaultImmersion float32 = 2
+	// DefaultClearance is the closest the tip comes to the bottom of a well
+	// when the well does not say.
+	DefaultClearance float32 = 1
+)
+
+// Well is the shape of a cell, from which the height of the liquid in it is
+// worked out. Lengths are in mm.
+type Well struct {
+	// Diameter is the width of a round well; square wells have XSize and
+	// YSize instead.
+	Diameter float32
+	XSize    float32
+	YSize    float32
+	Bottom   Bottom
+	// Clearance is the closest the tip may come to the bottom, or
+	// DefaultClearance if 0.
+	Clearance float32
+}
+
+// well returns the shape of the wells of l.
+func (l *Labware) well() *Well {
+	return &Well{Diameter: l.Diameter, XSize: l.XSize, YSize: l.YSize, Bottom: l.Bottom}
+}
+
+// SetWell gives every cell of m the shape w.
+func (m *Matrix) SetWell(w *Well) {
+	for _, row := range m.Cells {
+		for _, c := range row {
+			c.Well = w
+		}
+	}
+}
+
+// clearance is how close the tip comes to the bottom of w, which may be nil.
+func (w *Well) clearance() float32 {
+	if w != nil && w.Clearance > 0 {
+		return w.Clearance
+	}
+	return DefaultClearance
+}
+
+// radius is half the width of w, the narrower way for square wells.
+func (w *Well) radius() float64 {
+	if w.Diameter > 0 {
+		return float64(w.Diameter) / 2
+	}
+	return float64(min32(w.XSize, w.YSize)) / 2
+}
+
+// area is the cross section of w above its bottom, in mm².
+func (w *Well) area() float64 {
+	if w.Diameter > 0 {
+		r := float64(w.Diameter) / 2
+		return math.Pi * r * r
+	}
+	return float64(w.XSize * w.YSize)
+}
+
+// height is how high vol µL stands above the bottom of w. A V bottom is taken
+// to be a cone as tall as the well is wide and a U bottom a hemisphere.
+func (w *Well) height(vol float32) float32 {
+	v, a, r := float64(vol), w.area(), w.radius()
+	if a <= 0 || v <= 0 {
+		return 0
+	}
+	switch w.Bottom {
+	case VBottom:
+		h := 2 * r
+		cone := a * h / 3
+		if v < cone {
+			return float32(h * math.Cbrt(v/cone))
+		}
+		return float32(h + (v-cone)/a)
+	case RoundBottom:
+		bowl := a * r * 2 / 3
+		if v < bowl {
+			// a spherical cap of height h holds πh²(3r-h)/3; find h by
+			// bisection as it only grows with h
+			lo, hi := 0.0, r
+			for i := 0; i < 40; i++ {
+				h := (lo + hi) / 2
+				if math.Pi*h*h*(3*r-h)/3 < v {
+					lo = h
+				} else {
+					hi = h
+				}
+			}
+			return float32(lo)
+		}
+		return float32(r + (v-bowl)/a)
+	}
+	return float32(v / a)
+}
+
+// level is the Z of the surface of the liquid in c, whose rim is at at, once
+// change µL have been added to it (or drawn, if change is negative). It is
+// false when c's shape, depth or contents are not known.
+func (c *Cell) level(at Position, change float32) (float32, bool) {
+	if c == nil || c.Well == nil || c.Content == nil || c.Depth <= 0 {
+		return 0, false
+	}
+	vol := c.Volume() + change
+	if vol < 0 {
+		vol = 0
+	}
+	bottom := at.Z - c.Depth
+	return min32(bottom+c.Well.height(vol), at.Z), true
+}
+
+// floor is the lowest the tip may go in c, whose rim is at at: its bottom
+// plus the clearance of its well.
+func (c *Cell) floor(at Position) float32 {
+	return at.Z - c.Depth + c.Well.clearance()
+}
+
+// immersed returns where the tip goes to be depth below the surface of c once
+// change µL have been added to it, no lower than its floor. When the level is
+// not known the tip goes to the floor instead, and when the depth of c is not
+// known either, depth below its rim.
+func (c *Cell) immersed(at Position, change, depth float32) Position {
+	res := at
+	surface, ok := c.level(at, change)
+	switch {
+	case ok:
+		res.Z = max32(surface-depth, c.floor(at))
+	case c.depth() > 0:
+		res.Z = c.floor(at)
+	default:
+		res.Z -= depth
+	}
+	res.Z = hundredths(res.Z)
+	return res
+}
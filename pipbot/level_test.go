@@ -0,0 +1,71 @@
+package pipbot
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestImmersed(t *testing.T) {
+	lw, err := LookupLabware("opentrons_24_tuberack_eppendorf_1.5ml_safelock_snapcap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := lw.At("tubes", Position{X: 50, Y: 50, Z: 80})
+	tube := m.Cells[0][0]
+	full := m.Cells[0][1]
+	if err = full.Fill(1000); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name  string
+		c     *Cell
+		depth float32
+		want  float32
+	}{
+		// 80 - 37.9 deep + 1 clear of the bottom
+		{name: "unknown level", c: tube, depth: 0, want: 43.1},
+		{name: "unknown level, class depth", c: tube, depth: 2, want: 43.1},
+		{name: "unknown depth", c: &Cell{Position: tube.Position}, depth: 2, want: 78},
+		{name: "known level", c: full, depth: 2, want: hundredths(mustLevel(t, full) - 2)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.c.immersed(tc.c.Position, 0, tc.depth).Z; got != tc.want {
+				t.Errorf("Z%v, want Z%v", got, tc.want)
+			}
+		})
+	}
+}
+
+func mustLevel(t *testing.T, c *Cell) float32 {
+	t.Helper()
+	z, ok := c.level(c.Position, 0)
+	if !ok {
+		t.Fatal("level not known")
+	}
+	return z
+}
+
+func TestDrawSlowsBelowRim(t *testing.T) {
+	lw, err := LookupLabware("opentrons_24_tuberack_eppendorf_1.5ml_safelock_snapcap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := lw.At("tubes", Position{X: 50, Y: 50, Z: 80})
+	src, dest := m.Cells[0][0], m.Cells[0][1]
+	tr := NewTransfer(StockPipette(), nil, src.Position, dest.Position, 50, false)
+	tr.src, tr.dest = src, dest
+	tr.speeds = Speeds{Approach: 100}
+	phases, _, err := tr.phases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var draw []byte
+	for _, p := range phases {
+		if p.Phase == Aspirating {
+			draw = bytes.Join(p.lines, nil)
+		}
+	}
+	if !bytes.Contains(draw, []byte("G0 F100 Z43.1\n")) {
+		t.Errorf("the tip does not go down to the bottom of the tube at the approach rate:\n%s", draw)
+	}
+}
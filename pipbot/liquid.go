@@ -0,0 +1,133 @@
+package pipbot
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// LiquidClass is how to handle a liquid. Viscous liquids want slow plungers
+// and a pause before leaving the well; volatile ones want the tip pre-wet and
+// an air gap to stop them dripping. Settings left at zero, or nil, are the
+// pipette's.
+type LiquidClass struct {
+	Name string `yaml:"name" json:"name"`
+	// AspirateRate and DispenseRate are flow rates in µL/s.
+	AspirateRate float32 `yaml:"aspirate_rate,omitempty" json:"aspirate_rate,omitempty"`
+	DispenseRate float32 `yaml:"dispense_rate,omitempty" json:"dispense_rate,omitempty"`
+	// PreWet is how many times to draw up and dispense the transfer volume
+	// before aspirating it.
+	PreWet int `yaml:"pre_wet,omitempty" json:"pre_wet,omitempty"`
+	// Delay is how long to wait after aspirating, in seconds, before the tip
+	// leaves the liquid.
+	Delay float32 `yaml:"delay,omitempty" json:"delay,omitempty"`
+	// AirGap and Blowout are the pipette's air gap and blowout volumes in µL.
+	AirGap  *float32 `yaml:"air_gap,omitempty" json:"air_gap,omitempty"`
+	Blowout *float32 `yaml:"blowout,omitempty" json:"blowout,omitempty"`
+	// TouchTip is how far in mm to move the tip each way against the wall
+	// just inside the rim after dispensing, to knock off the last drop. 0
+	// does not touch the tip.
+	TouchTip float32 `yaml:"touch_tip,omitempty" json:"touch_tip,omitempty"`
+	// AspirateDepth and DispenseDepth are how far below the liquid surface
+	// the tip goes, in mm. Where the level of the liquid is known an
+	// AspirateDepth of 0 is DefaultImmersion, and the dispense depth is below
+	// the level the liquid will reach. Elsewhere they are below the rim.
+	AspirateDepth float32 `yaml:"aspirate_depth,omitempty" json:"aspirate_depth,omitempty"`
+	DispenseDepth float32 `yaml:"dispense_depth,omitempty" json:"dispense_depth,omitempty"`
+	// Speeds are the gantry speeds while aspirating and dispensing, such as
+	// a slow approach into the well.
+	Speeds Speeds `yaml:"speeds,omitempty" json:"speeds,omitempty"`
+}
+
+// touchTipDepth is how far inside the rim of a well the tip touches the wall.
+const touchTipDepth float32 = 1
+
+// Validate checks that c is usable.
+func (c *LiquidClass) Validate() error {
+	switch {
+	case c.Name == "":
+		return fmt.Errorf("liquid class has no name")
+	case c.AspirateRate < 0 || c.DispenseRate < 0:
+		return fmt.Errorf("liquid class %q: flow rates cannot be negative", c.Name)
+	case c.PreWet < 0 || c.Delay < 0 || c.TouchTip < 0 || c.AspirateDepth < 0 || c.DispenseDepth < 0:
+		return fmt.Errorf("liquid class %q: settings cannot be negative", c.Name)
+	case c.AirGap != nil && *c.AirGap < 0, c.Blowout != nil && *c.Blowout < 0:
+		return fmt.Errorf("liquid class %q: volumes cannot be negative", c.Name)
+	}
+	if err := c.Speeds.Validate(); err != nil {
+		return fmt.Errorf("liquid class %q: %w", c.Name, err)
+	}
+	return nil
+}
+
+// pipette returns p as c would have it, or p itself if c is nil.
+func (c *LiquidClass) pipette(p *Pipette) *Pipette {
+	if c == nil {
+		return p
+	}
+	res := *p
+	if c.AspirateRate > 0 {
+		res.AspirateRate = c.AspirateRate
+	}
+	if c.DispenseRate > 0 {
+		res.DispenseRate = c.DispenseRate
+	}
+	if c.AirGap != nil {
+		res.AirGap = *c.AirGap
+	}
+	if c.Blowout != nil {
+		res.Blowout = *c.Blowout
+	}
+	return &res
+}
+
+func ul(v float32) *float32 {
+	return &v
+}
+
+var (
+	liquidMu sync.RWMutex
+	liquids  = map[string]*LiquidClass{}
+)
+
+// RegisterLiquidClass adds c to the library, replacing any class with the
+// same name.
+func RegisterLiquidClass(c *LiquidClass) {
+	liquidMu.Lock()
+	defer liquidMu.Unlock()
+	liquids[c.Name] = c
+}
+
+// LookupLiquidClass returns the liquid class called name.
+func LookupLiquidClass(name string) (*LiquidClass, error) {
+	liquidMu.RLock()
+	defer liquidMu.RUnlock()
+	c, ok := liquids[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown liquid class %q", name)
+	}
+	return c, nil
+}
+
+// LiquidClassNames lists the library in alphabetical order.
+func LiquidClassNames() []string {
+	liquidMu.RLock()
+	defer liquidMu.RUnlock()
+	res := make([]string, 0, len(liquids))
+	for n := range liquids {
+		res = append(res, n)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func init() {
+	for _, c := range []*LiquidClass{
+		{Name: "water"},
+		{Name: "glycerol", AspirateRate: 10, DispenseRate: 10, Delay: 2, Blowout: ul(20), TouchTip: 2, AspirateDepth: 2,
+			Speeds: Speeds{Approach: 300}},
+		{Name: "ethanol", DispenseRate: 100, PreWet: 2, AirGap: ul(10), Blowout: ul(10), AspirateDepth: 1},
+	} {
+		RegisterLiquidClass(c)
+	}
+}
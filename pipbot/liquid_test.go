@@ -0,0 +1,133 @@
+package pipbot
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// forgetLiquidClass drops name from the library when t is done, for classes
+// a test registers.
+func forgetLiquidClass(t *testing.T, name string) {
+	t.Cleanup(func() {
+		liquidMu.Lock()
+		defer liquidMu.Unlock()
+		delete(liquids, name)
+	})
+}
+
+func TestLookupLiquidClass(t *testing.T) {
+	for _, name := range []string{"water", "glycerol", "ethanol"} {
+		c, err := LookupLiquidClass(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Name != name {
+			t.Errorf("looked up %q, got %q", name, c.Name)
+		}
+		if err = c.Validate(); err != nil {
+			t.Errorf("built in class: %v", err)
+		}
+	}
+	if _, err := LookupLiquidClass("honey"); err == nil || err.Error() != `unknown liquid class "honey"` {
+		t.Errorf("got %v for a class that is not in the library", err)
+	}
+	if names := LiquidClassNames(); !sort.StringsAreSorted(names) {
+		t.Errorf("names %v are not in order", names)
+	}
+
+	forgetLiquidClass(t, "test serum")
+	RegisterLiquidClass(&LiquidClass{Name: "test serum", AspirateRate: 50})
+	RegisterLiquidClass(&LiquidClass{Name: "test serum", AspirateRate: 20})
+	c, err := LookupLiquidClass("test serum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.AspirateRate != 20 {
+		t.Errorf("aspirate rate %v, want the class registered last", c.AspirateRate)
+	}
+}
+
+func TestLiquidClassValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		c    LiquidClass
+		want string
+	}{
+		{name: "no name", c: LiquidClass{AspirateRate: 10}, want: "liquid class has no name"},
+		{name: "rate", c: LiquidClass{Name: "x", DispenseRate: -1}, want: `liquid class "x": flow rates cannot be negative`},
+		{name: "pre wet", c: LiquidClass{Name: "x", PreWet: -1}, want: `liquid class "x": settings cannot be negative`},
+		{name: "depth", c: LiquidClass{Name: "x", DispenseDepth: -2}, want: `liquid class "x": settings cannot be negative`},
+		{name: "air gap", c: LiquidClass{Name: "x", AirGap: ul(-5)}, want: `liquid class "x": volumes cannot be negative`},
+		{name: "blowout", c: LiquidClass{Name: "x", Blowout: ul(-5)}, want: `liquid class "x": volumes cannot be negative`},
+		{name: "speeds", c: LiquidClass{Name: "x", Speeds: Speeds{Approach: -100}}, want: `liquid class "x": speeds cannot be negative`},
+		{name: "zero air gap", c: LiquidClass{Name: "x", AirGap: ul(0), Blowout: ul(0)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.c.Validate()
+			switch {
+			case tc.want == "" && err != nil:
+				t.Errorf("got %v", err)
+			case tc.want != "" && (err == nil || err.Error() != tc.want):
+				t.Errorf("got %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestLiquidClassPipette(t *testing.T) {
+	p := StockPipette()
+	p.AirGap, p.Blowout = 5, 5
+	var none *LiquidClass
+	if none.pipette(p) != p {
+		t.Error("no class changed the pipette")
+	}
+	c := &LiquidClass{Name: "x", DispenseRate: 100, AirGap: ul(0), Blowout: ul(10)}
+	got := c.pipette(p)
+	want := *p
+	want.DispenseRate, want.AirGap, want.Blowout = 100, 0, 10
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("got %+v\nwant %+v", *got, want)
+	}
+	if p.DispenseRate == 100 || p.AirGap != 5 {
+		t.Error("the class changed the pipette it was given")
+	}
+}
+
+func TestDeckLiquidClasses(t *testing.T) {
+	forgetLiquidClass(t, "test oil")
+	tip := Tip
+	d := &Deck{
+		LiquidClasses: []*LiquidClass{{Name: "test oil", AspirateRate: 5}},
+		Matrices: []MatrixConfig{{
+			Name: "tubes", Home: Position{X: 20, Y: 20, Z: 70}, RowSpace: 20, ColSpace: 20, Rows: 2, Cols: 2,
+			LiquidClass: "water", LiquidClasses: map[string]string{"B2": "test oil"},
+		}, {
+			Name: "tips", Kind: &tip, Role: Tips, Home: Position{X: 100, Y: 20, Z: 70}, RowSpace: 9, ColSpace: 9, Rows: 1, Cols: 1,
+		}},
+	}
+	l, err := d.Layout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells := l.Matrices[0].Cells
+	for _, tc := range []struct {
+		well string
+		c    *Cell
+		want string
+	}{{"A1", cells[0][0], "water"}, {"B1", cells[1][0], "water"}, {"B2", cells[1][1], "test oil"}} {
+		if tc.c.Liquid == nil || tc.c.Liquid.Name != tc.want {
+			t.Errorf("%v holds %v, want %v", tc.well, tc.c.Liquid, tc.want)
+		}
+	}
+
+	d.Matrices[0].LiquidClasses["A2"] = "honey"
+	if _, err = d.Layout(); err == nil || err.Error() != `matrix "tubes": A2: unknown liquid class "honey"` {
+		t.Errorf("got %v for a well of an unknown class", err)
+	}
+	d.Matrices[0].LiquidClasses = nil
+	d.LiquidClasses[0].AspirateRate = -5
+	if _, err = d.Layout(); err == nil || err.Error() != `liquid class "test oil": flow rates cannot be negative` {
+		t.Errorf("got %v for a bad class in the deck", err)
+	}
+}
@@ -0,0 +1,331 @@
+package pipbot
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultTimeout is how long Marlin waits for the firmware to say anything
+	// before giving up on a line. Every line it prints restarts the clock, so
+	// busy keepalives and the temperature reports of a heat wait both count.
+	DefaultTimeout = 10 * time.Second
+	// historySize is how many sent lines are kept around for resend requests.
+	historySize = 64
+	// resetRetries is how often the M110 line reset is retried while the board
+	// is still booting after the port was opened.
+	resetRetries = 3
+)
+
+type replyKind uint8
+
+const (
+	replyOk replyKind = iota
+	replyResend
+	replyBusy
+	replyError
+	// replySync is the marker resync waits for.
+	replySync
+)
+
+type reply struct {
+	kind replyKind
+	line int
+	text string
+}
+
+// Marlin wraps a Transport with Marlin's serial line protocol. Every line is
+// numbered and checksummed (N123 G0 X1*cs), and the next line is only sent
+// once the firmware has acknowledged the previous one with "ok", so long
+// protocols never overflow the printer's receive buffer. Resend requests are
+// answered from a short history of sent lines and "busy: processing" is
+// treated as a keepalive. After a line times out its ok may still turn up, so
+// the next Write first brings the firmware back in step with the sender.
+//
+// Anything the firmware prints that is not part of the handshake (echo:,
+// temperature reports, ...) is available through Read.
+type Marlin struct {
+	Timeout time.Duration
+	t       Transport
+	mu      sync.Mutex
+	line    int
+	reset   bool
+	history map[int]string
+	replies chan reply
+	rx      chan []byte
+	pending []byte
+	// activity is signalled for every line the firmware prints.
+	activity chan struct{}
+	// behind is set when a line timed out, as its ok may arrive late and be
+	// taken for the next line's. Write resyncs before sending anything else.
+	behind bool
+	syncs  int
+	// marker is the line resync waits for the firmware to echo.
+	marker atomic.Pointer[string]
+}
+
+// NewMarlin starts reading replies from t. The line counter is reset with
+// M110 before the first command is sent.
+func NewMarlin(t Transport) *Marlin {
+	m := &Marlin{
+		Timeout:  DefaultTimeout,
+		t:        t,
+		history:  make(map[int]string),
+		replies:  make(chan reply, historySize),
+		rx:       make(chan []byte, historySize),
+		activity: make(chan struct{}, 1),
+	}
+	go m.read()
+	return m
+}
+
+// checksum is the XOR of every byte in s, as Marlin computes it.
+func checksum(s string) byte {
+	var cs byte
+	for i := 0; i < len(s); i++ {
+		cs ^= s[i]
+	}
+	return cs
+}
+
+// frame numbers and checksums cmd as line n.
+func frame(n int, cmd string) string {
+	s := fmt.Sprintf("N%d %s", n, cmd)
+	return fmt.Sprintf("%s*%d\n", s, checksum(s))
+}
+
+func parseReply(line string) (reply, bool) {
+	switch {
+	case strings.HasPrefix(line, "ok"):
+		return reply{kind: replyOk, text: line}, true
+	case strings.HasPrefix(line, "Resend:"), strings.HasPrefix(line, "rs "):
+		f := strings.FieldsFunc(line, func(r rune) bool { return r == ':' || r == ' ' })
+		n, err := strconv.Atoi(f[len(f)-1])
+		if err != nil {
+			return reply{kind: replyError, text: line}, true
+		}
+		return reply{kind: replyResend, line: n, text: line}, true
+	case strings.Contains(line, "busy:"):
+		return reply{kind: replyBusy, text: line}, true
+	case strings.HasPrefix(line, "Error:"), strings.HasPrefix(line, "!!"):
+		return reply{kind: replyError, text: line}, true
+	}
+	return reply{}, false
+}
+
+func (m *Marlin) read() {
+	defer close(m.replies)
+	defer close(m.rx)
+	scan := bufio.NewScanner(m.t)
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+		if line == "" {
+			continue
+		}
+		select {
+		case m.activity <- struct{}{}:
+		default:
+		}
+		if mk := m.marker.Load(); mk != nil && line == *mk {
+			m.marker.Store(nil)
+			m.replies <- reply{kind: replySync, text: line}
+			continue
+		}
+		if r, ok := parseReply(line); ok {
+			m.replies <- r
+			if r.kind != replyError {
+				continue
+			}
+		}
+		// nobody may be listening, so drop output rather than stall the acks
+		select {
+		case m.rx <- []byte(line + "\n"):
+		default:
+		}
+	}
+}
+
+// Read returns whatever the firmware printed that was not an acknowledgement.
+func (m *Marlin) Read(p []byte) (int, error) {
+	if len(m.pending) == 0 {
+		line, ok := <-m.rx
+		if !ok {
+			return 0, io.EOF
+		}
+		m.pending = line
+	}
+	n := copy(p, m.pending)
+	m.pending = m.pending[n:]
+	return n, nil
+}
+
+// Write sends every command in p, one line at a time, waiting for each to be
+// acknowledged. Comments and blank lines are dropped.
+func (m *Marlin) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.behind {
+		if err := m.resync(); err != nil {
+			return 0, err
+		}
+	}
+	if !m.reset {
+		if err := m.resetLine(); err != nil {
+			return 0, err
+		}
+		m.reset = true
+	}
+	for _, l := range bytes.Split(p, []byte("\n")) {
+		cmd := string(l)
+		if i := strings.IndexByte(cmd, ';'); i >= 0 {
+			cmd = cmd[:i]
+		}
+		cmd = strings.TrimSpace(cmd)
+		if cmd == "" {
+			continue
+		}
+		if err := m.send(cmd); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close closes the underlying Transport, which also stops the reader.
+func (m *Marlin) Close() error {
+	return m.t.Close()
+}
+
+func (m *Marlin) resetLine() error {
+	m.line = 0
+	m.history = map[int]string{0: frame(0, "M110 N0")}
+	var err error
+	for i := 0; i < resetRetries; i++ {
+		if m.behind {
+			err = m.resync()
+		} else {
+			err = m.transmit(0)
+		}
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// resync brings the firmware back in step after a timeout. The line counter
+// is reset with an unnumbered M110 and a marker is echoed with M118; every
+// reply before the marker is dropped, late oks included.
+func (m *Marlin) resync() error {
+	m.syncs++
+	mk := fmt.Sprintf("pipbot sync %d", m.syncs)
+	m.marker.Store(&mk)
+	if _, err := io.WriteString(m.t, "M110 N0\nM118 "+mk+"\n"); err != nil {
+		return err
+	}
+	timer := time.NewTimer(m.Timeout)
+	defer timer.Stop()
+	for waiting := true; waiting; {
+		select {
+		case r, ok := <-m.replies:
+			if !ok {
+				return io.ErrUnexpectedEOF
+			}
+			waiting = r.kind != replySync || r.text != mk
+		case <-m.activity:
+			restart(timer, m.Timeout)
+		case <-timer.C:
+			return fmt.Errorf("%w: the firmware did not echo %q after %v", ErrTimeout, mk, m.Timeout)
+		}
+	}
+	// the ok for the M118 comes after its echo
+	if _, err := m.await(0); err != nil {
+		return err
+	}
+	m.line, m.history = 0, map[int]string{}
+	m.behind, m.reset = false, true
+	return nil
+}
+
+// restart sets t to fire after d from now.
+func restart(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		<-t.C
+	}
+	t.Reset(d)
+}
+
+func (m *Marlin) send(cmd string) error {
+	m.line++
+	m.history[m.line] = frame(m.line, cmd)
+	delete(m.history, m.line-historySize)
+	return m.transmit(m.line)
+}
+
+// transmit writes line n and keeps going until every line up to m.line has
+// been acknowledged, replaying history whenever the firmware asks for it.
+func (m *Marlin) transmit(n int) error {
+	for n <= m.line {
+		l, ok := m.history[n]
+		if !ok {
+			return &FirmwareError{Msg: fmt.Sprintf("asked to resend line %v which is no longer in history", n)}
+		}
+		if _, err := io.WriteString(m.t, l); err != nil {
+			return err
+		}
+		resend, err := m.await(n)
+		if err != nil {
+			return err
+		}
+		if resend >= 0 {
+			n = resend
+			continue
+		}
+		n++
+	}
+	return nil
+}
+
+// await blocks until the firmware acknowledges line n. It returns the line to
+// resend from if the firmware asked for one, or -1.
+func (m *Marlin) await(n int) (int, error) {
+	resend := -1
+	var lastErr string
+	timer := time.NewTimer(m.Timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case r, ok := <-m.replies:
+			if !ok {
+				return 0, io.ErrUnexpectedEOF
+			}
+			switch r.kind {
+			case replyOk:
+				return resend, nil
+			case replyResend:
+				resend = r.line
+			case replyError:
+				lastErr = r.text
+				if strings.HasPrefix(r.text, "!!") || strings.Contains(r.text, "halted") {
+					return 0, &FirmwareError{Msg: r.text}
+				}
+			}
+			restart(timer, m.Timeout)
+		case <-m.activity:
+			restart(timer, m.Timeout)
+		case <-timer.C:
+			m.behind = true
+			if lastErr != "" {
+				return 0, fmt.Errorf("%w: no ok for line %v after %v (last error %q)", ErrTimeout, n, m.Timeout, lastErr)
+			}
+			return 0, fmt.Errorf("%w: no ok for line %v after %v", ErrTimeout, n, m.Timeout)
+		}
+	}
+}
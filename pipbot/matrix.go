@@ -1,5 +1,11 @@
 package pipbot
 
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
 type CellType uint8
 
 const (
@@ -9,33 +15,224 @@ const (
 	Unknown
 )
 
-// Mixture describes what is in a cell.
-// Mixtures can be stocks, mixtures of mixtures, dilutions, etc.
-type Mixture struct {
-	Contents map[Cell]float32
+var cellTypeNames = map[CellType]string{
+	Tip:      "tip",
+	Stock:    "stock",
+	Standard: "standard",
+	Unknown:  "unknown",
+}
+
+func (c CellType) String() string {
+	if n, ok := cellTypeNames[c]; ok {
+		return n
+	}
+	return fmt.Sprintf("CellType(%d)", c)
+}
+
+func (c CellType) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
 }
 
+func (c *CellType) UnmarshalText(text []byte) error {
+	for k, n := range cellTypeNames {
+		if strings.EqualFold(n, string(text)) {
+			*c = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown kind %q", text)
+}
+
+// Role is the part a Matrix plays in a protocol. Plate map plans draw from the
+// Source matrix into the Destination matrix, and tips come from Tips.
+type Role string
+
+const (
+	NoRole      Role = ""
+	Source      Role = "source"
+	Destination Role = "destination"
+	Tips        Role = "tips"
+)
+
 // Cell is the fundamental discrete addressable unit in the system.
 // A cell can be a pipette tip position, an individual well of a plate, etc.
+// Its Position is the center of the rim of the well, or where the tip picks up
+// for a tip rack. Cells of unknown Depth have only that height to go on, so
+// the tip works at the rim.
 type Cell struct {
 	Kind CellType
-	*Position
+	Position
+	// Content is what is in the cell, or nil if that is not known.
 	Content *Mixture
+	// MaxVolume is how much the cell holds in µL, 0 if that is not known.
+	MaxVolume float32
+	// Depth is how far the well goes down from Position.Z, which is its rim.
+	Depth float32
+	// Well is the shape of the cell, or nil if it is not known.
+	Well *Well
+	// Liquid is how to handle what is drawn from the cell, or nil for the
+	// pipette's settings.
+	Liquid *LiquidClass
+	// Concentration is that of the stock the cell is filled with, or nil if
+	// it is not known.
+	Concentration *Quantity
+}
+
+// depth is how deep c is, 0 for a nil cell.
+func (c *Cell) depth() float32 {
+	if c == nil {
+		return 0
+	}
+	return c.Depth
+}
+
+// SetDepth sets how deep the wells of m are.
+func (m *Matrix) SetDepth(depth float32) {
+	m.Depth = depth
+	for _, row := range m.Cells {
+		for _, c := range row {
+			c.Depth = depth
+		}
+	}
+}
+
+// SetMaxVolume sets how much each cell of m holds.
+func (m *Matrix) SetMaxVolume(vol float32) {
+	for _, row := range m.Cells {
+		for _, c := range row {
+			c.MaxVolume = vol
+		}
+	}
 }
 
 // Matrix is an aggregate of Cells. This can be a well plate, pipette tip box,
 // tube rack, etc.
 type Matrix struct {
-	Name    string
-	Cells   [][]*Cell
-	Home    *Position
-	Rows    int
-	Columns int
+	Name     string
+	Kind     CellType
+	Role     Role
+	Cells    [][]*Cell
+	Home     Position
+	Rows     int
+	Columns  int
+	RowSpace float32
+	ColSpace float32
+	// Depth is how far the wells go down from their rims, or 0 if that is
+	// not known.
+	Depth float32
+	// Rotation is how far the columns are turned counterclockwise from the X
+	// axis, and Skew how far the rows lean from square to the columns, both in
+	// degrees.
+	Rotation float32
+	Skew     float32
+	// Transform places the cells instead of Home, the spacings and the angles
+	// when it is set. Those are kept in step with it.
+	Transform *Transform
+	// Pipette is the pipette a tip matrix holds tips for.
+	Pipette string
+	// Labware is the definition the matrix was made from, if any.
+	Labware *Labware
+	// Top is the highest Z the tip can touch the matrix at, for lids or
+	// tubes standing above the rims. The highest rim is used if it is 0.
+	Top float32
+}
+
+// Rect is an axis aligned area of the bed.
+type Rect struct {
+	MinX, MinY float32
+	MaxX, MaxY float32
+}
+
+// Overlaps reports whether r and o share any area. Touching edges do not count.
+func (r Rect) Overlaps(o Rect) bool {
+	return r.MinX < o.MaxX && o.MinX < r.MaxX && r.MinY < o.MaxY && o.MinY < r.MaxY
+}
+
+// corners returns the positions of the four corner cells of m.
+func (m *Matrix) corners() []Position {
+	r, c := m.Rows-1, m.Columns-1
+	return []Position{m.Cells[0][0].Position, m.Cells[0][c].Position, m.Cells[r][0].Position, m.Cells[r][c].Position}
+}
+
+// Footprint is the area m covers on the bed: its outermost cell centers plus
+// half a pitch on every side.
+func (m *Matrix) Footprint() Rect {
+	res := Rect{MinX: m.Home.X, MinY: m.Home.Y, MaxX: m.Home.X, MaxY: m.Home.Y}
+	for _, p := range m.corners() {
+		res.MinX = min32(res.MinX, p.X)
+		res.MinY = min32(res.MinY, p.Y)
+		res.MaxX = max32(res.MaxX, p.X)
+		res.MaxY = max32(res.MaxY, p.Y)
+	}
+	res.MinX -= m.ColSpace / 2
+	res.MinY -= m.RowSpace / 2
+	res.MaxX += m.ColSpace / 2
+	res.MaxY += m.RowSpace / 2
+	return res
+}
+
+func min32(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Rotate turns m about its A1 well by rotation degrees and leans its rows by
+// skew degrees, moving every cell to match. It drops any Transform.
+func (m *Matrix) Rotate(rotation, skew float32) {
+	m.Rotation, m.Skew = rotation, skew
+	m.Transform = nil
+	m.place()
 }
 
-func (m *Matrix) Channel() <-chan *Position {
-	res := make(chan *Position, m.Rows*m.Columns)
+// SetTransform places m with t, tilt and all.
+func (m *Matrix) SetTransform(t Transform) {
+	m.Transform = &t
+	m.Home = t.Origin
+	m.RowSpace, m.ColSpace = t.Spacing()
+	m.Rotation, m.Skew = t.Rotation()
+	m.place()
+}
+
+// transform is the Transform that places m, made up from Home, the spacings
+// and the angles if m has none.
+func (m *Matrix) transform() Transform {
+	if m.Transform != nil {
+		return *m.Transform
+	}
+	theta := float64(m.Rotation) * math.Pi / 180
+	psi := theta + float64(m.Skew)*math.Pi/180
+	return Transform{
+		Origin: m.Home,
+		Col:    Position{X: float32(math.Cos(theta)) * m.ColSpace, Y: float32(math.Sin(theta)) * m.ColSpace},
+		Row:    Position{X: -float32(math.Sin(psi)) * m.RowSpace, Y: float32(math.Cos(psi)) * m.RowSpace},
+	}
+}
+
+// place puts every cell where the transform of m says.
+func (m *Matrix) place() {
+	t := m.transform()
+	for row := 0; row < m.Rows; row++ {
+		for col := 0; col < m.Columns; col++ {
+			m.Cells[row][col].Position = t.Apply(row, col)
+		}
+	}
+}
+
+// Channel yields every cell position in row order and is closed once the
+// matrix has been used up.
+func (m *Matrix) Channel() <-chan Position {
+	res := make(chan Position, m.Rows*m.Columns)
 	go func() {
+		defer close(res)
 		for row := 0; row < m.Rows; row++ {
 			for col := 0; col < m.Columns; col++ {
 				res <- m.Cells[row][col].Position
@@ -45,32 +242,211 @@ func (m *Matrix) Channel() <-chan *Position {
 	return res
 }
 
+// ParseWell turns a well name like "A1" or "p24" into a zero based row and
+// column. Rows are letters, A-Z then AA, AB and so on.
+func ParseWell(name string) (row, col int, err error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	i := 0
+	for i < len(name) && name[i] >= 'A' && name[i] <= 'Z' {
+		row = row*26 + int(name[i]-'A'+1)
+		i++
+	}
+	if i == 0 || i == len(name) {
+		return 0, 0, fmt.Errorf("bad well name %q", name)
+	}
+	for _, c := range name[i:] {
+		if c < '0' || c > '9' {
+			return 0, 0, fmt.Errorf("bad well name %q", name)
+		}
+		col = col*10 + int(c-'0')
+	}
+	if col == 0 {
+		return 0, 0, fmt.Errorf("bad well name %q", name)
+	}
+	return row - 1, col - 1, nil
+}
+
+// WellName is the inverse of ParseWell.
+func WellName(row, col int) string {
+	return fmt.Sprintf("%s%d", rowName(row), col+1)
+}
+
+// rowName is the letters of a zero based row.
+func rowName(row int) string {
+	var r []byte
+	for n := row + 1; n > 0; n = (n - 1) / 26 {
+		r = append([]byte{byte('A' + (n-1)%26)}, r...)
+	}
+	return string(r)
+}
+
+// cell returns the cell at row, col of m.
+func (m *Matrix) cell(row, col int) (*Cell, error) {
+	if row < 0 || row >= m.Rows || col < 0 || col >= m.Columns {
+		return nil, fmt.Errorf("%w: %v has no cell at row %v column %v", ErrOutOfBounds, m.Name, row, col)
+	}
+	return m.Cells[row][col], nil
+}
+
+// Well returns the cell called name, e.g. "B3".
+func (m *Matrix) Well(name string) (*Cell, error) {
+	row, col, err := ParseWell(name)
+	if err != nil {
+		return nil, err
+	}
+	return m.cell(row, col)
+}
+
 // Layout describes how individual Matrix units are arranged on the build plate.
 type Layout struct {
 	Matrices []*Matrix
+	// TravelMargin is how far above the matrices the tip crosses the deck,
+	// or DefaultTravelMargin if 0.
+	TravelMargin float32
+	// Speeds are the deck's speeds on top of DefaultSpeeds.
+	Speeds Speeds
 }
 
-func NewMatrix(kind CellType, name string, home *Position, rowSpace, colSpace float32, nRow,
+// Matrix returns the matrix called name.
+func (l *Layout) Matrix(name string) (*Matrix, error) {
+	for _, m := range l.Matrices {
+		if m.Name == name {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("no matrix named %q on the deck", name)
+}
+
+// SplitCell splits a reference like "plate:B3" into its matrix and well.
+func SplitCell(ref string) (matrix, well string, err error) {
+	matrix, well, ok := strings.Cut(ref, ":")
+	if !ok {
+		return "", "", fmt.Errorf("bad cell %q, expected matrix:well", ref)
+	}
+	if _, _, err = ParseWell(well); err != nil {
+		return "", "", err
+	}
+	return matrix, strings.ToUpper(well), nil
+}
+
+// Cell returns the cell a reference like "plate:B3" points to.
+func (l *Layout) Cell(ref string) (*Cell, error) {
+	name, well, err := SplitCell(ref)
+	if err != nil {
+		return nil, err
+	}
+	m, err := l.Matrix(name)
+	if err != nil {
+		return nil, err
+	}
+	return m.Well(well)
+}
+
+// Role returns the first matrix playing role.
+func (l *Layout) Role(role Role) (*Matrix, error) {
+	for _, m := range l.Matrices {
+		if m.Role == role {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("no %v matrix on the deck", role)
+}
+
+// TipBox returns the first tip matrix holding tips for pipette.
+func (l *Layout) TipBox(pipette string) (*Matrix, error) {
+	boxes, err := l.TipBoxes(pipette)
+	if err != nil {
+		return nil, err
+	}
+	return boxes[0], nil
+}
+
+// TipBoxes returns the tip matrices holding tips for pipette, in the order
+// their tips are used.
+func (l *Layout) TipBoxes(pipette string) ([]*Matrix, error) {
+	var res []*Matrix
+	for _, m := range l.Matrices {
+		if m.Kind == Tip && m.Pipette == pipette {
+			res = append(res, m)
+		}
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("%w: no tip box for pipette %q", ErrOutOfTips, pipette)
+	}
+	return res, nil
+}
+
+// Validate checks that matrix names are unique, that no two matrices overlap
+// on the bed, that every matrix fits in the build volume and that each of
+// pipettes has at least one tip box.
+func (l *Layout) Validate(pipettes ...string) error {
+	seen := make(map[string]bool)
+	for i, m := range l.Matrices {
+		if m.Rows <= 0 || m.Columns <= 0 {
+			return fmt.Errorf("matrix %q: needs at least one row and column", m.Name)
+		}
+		if seen[m.Name] {
+			return fmt.Errorf("matrix %q is declared twice", m.Name)
+		}
+		seen[m.Name] = true
+		for _, p := range m.corners() {
+			if err := p.check(); err != nil {
+				return fmt.Errorf("matrix %q: %w", m.Name, err)
+			}
+		}
+		for _, o := range l.Matrices[:i] {
+			if m.Footprint().Overlaps(o.Footprint()) {
+				return fmt.Errorf("matrices %q and %q overlap", o.Name, m.Name)
+			}
+		}
+	}
+	for _, p := range pipettes {
+		n := 0
+		for _, m := range l.Matrices {
+			if m.Kind == Tip && m.Pipette == p {
+				n++
+			}
+		}
+		if n == 0 {
+			return fmt.Errorf("pipette %q has no tip box", p)
+		}
+	}
+	for _, m := range l.Matrices {
+		if m.Kind != Tip || m.Pipette == "" {
+			continue
+		}
+		known := false
+		for _, p := range pipettes {
+			known = known || p == m.Pipette
+		}
+		if !known {
+			return fmt.Errorf("tip box %q is for unknown pipette %q", m.Name, m.Pipette)
+		}
+	}
+	return nil
+}
+
+func NewMatrix(kind CellType, name string, home Position, rowSpace, colSpace float32, nRow,
 	nCol int) *Matrix {
 	m := &Matrix{
-		Name:    name,
-		Cells:   make([][]*Cell, nRow),
-		Home:    home,
-		Rows:    nRow,
-		Columns: nCol,
+		Name:     name,
+		Kind:     kind,
+		Cells:    make([][]*Cell, nRow),
+		Home:     home,
+		Rows:     nRow,
+		Columns:  nCol,
+		RowSpace: rowSpace,
+		ColSpace: colSpace,
+	}
+	if kind == Tip {
+		m.Role = Tips
 	}
 	for row := 0; row < nRow; row++ {
 		m.Cells[row] = make([]*Cell, nCol)
 		for col := 0; col < nCol; col++ {
-			m.Cells[row][col] = &Cell{
-				Kind: kind,
-				Position: &Position{
-					X: home.X + (float32(col) * colSpace),
-					Y: home.Y + (float32(row) * rowSpace),
-					Z: home.Z,
-				},
-			}
+			m.Cells[row][col] = &Cell{Kind: kind}
 		}
 	}
+	m.place()
 	return m
 }
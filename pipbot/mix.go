@@ -0,0 +1,143 @@
+package pipbot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"pipbot/graph/model"
+)
+
+// Mix draws Volume up and pushes it back out Cycles times to mix a well,
+// before a transfer aspirates from it or after one dispenses into it.
+type Mix struct {
+	Cycles int     `yaml:"cycles" json:"cycles"`
+	Volume float32 `yaml:"volume" json:"volume"`
+	// Height is how far above the bottom of the well to mix, in mm. At 0 the
+	// tip mixes where the transfer aspirates or dispenses.
+	Height float32 `yaml:"height,omitempty" json:"height,omitempty"`
+	// Rate is the flow rate both ways in µL/s, or the pipette's own rates if
+	// 0.
+	Rate float32 `yaml:"rate,omitempty" json:"rate,omitempty"`
+}
+
+// ParseMix reads a mix written as cycles x volume, then optionally @height
+// and /rate: "3x50", "3x50@2" or "3x50@2/100".
+func ParseMix(s string) (*Mix, error) {
+	bad := fmt.Errorf("bad mix %q, expected cycles x volume[@height][/rate] such as 3x50@2", s)
+	s = strings.ReplaceAll(strings.ToLower(s), " ", "")
+	s, rate, hasRate := strings.Cut(s, "/")
+	s, height, hasHeight := strings.Cut(s, "@")
+	cycles, vol, ok := strings.Cut(s, "x")
+	if !ok {
+		return nil, bad
+	}
+	m := &Mix{}
+	var err error
+	if m.Cycles, err = strconv.Atoi(cycles); err != nil {
+		return nil, bad
+	}
+	for _, f := range []struct {
+		dst *float32
+		src string
+		has bool
+	}{{&m.Volume, vol, true}, {&m.Height, height, hasHeight}, {&m.Rate, rate, hasRate}} {
+		if !f.has {
+			continue
+		}
+		v, err := strconv.ParseFloat(f.src, 32)
+		if err != nil {
+			return nil, bad
+		}
+		*f.dst = float32(v)
+	}
+	return m, m.validate()
+}
+
+// String writes m the way ParseMix reads it.
+func (m *Mix) String() string {
+	s := fmt.Sprintf("%vx%v", m.Cycles, m.Volume)
+	if m.Height != 0 {
+		s += fmt.Sprintf("@%v", m.Height)
+	}
+	if m.Rate != 0 {
+		s += fmt.Sprintf("/%v", m.Rate)
+	}
+	return s
+}
+
+// UnmarshalYAML reads a mix either as a mapping or in the short form ParseMix
+// reads.
+func (m *Mix) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		p, err := ParseMix(n.Value)
+		if err != nil {
+			return err
+		}
+		*m = *p
+		return nil
+	}
+	type plain Mix
+	if err := n.Decode((*plain)(m)); err != nil {
+		return err
+	}
+	return m.validate()
+}
+
+func (m *Mix) validate() error {
+	if m.Cycles < 1 || m.Volume <= 0 || m.Height < 0 || m.Rate < 0 {
+		return fmt.Errorf("bad mix %v: needs at least one cycle and a positive volume", m)
+	}
+	return nil
+}
+
+// model converts m to the GraphQL model.
+func (m *Mix) model() *model.Mix {
+	if m == nil {
+		return nil
+	}
+	res := &model.Mix{Cycles: m.Cycles, Volume: float64(m.Volume)}
+	if m.Height != 0 {
+		h := float64(m.Height)
+		res.Height = &h
+	}
+	if m.Rate != 0 {
+		r := float64(m.Rate)
+		res.Rate = &r
+	}
+	return res
+}
+
+// mixFrom converts a mix in the GraphQL model.
+func mixFrom(m *model.Mix) (*Mix, error) {
+	if m == nil {
+		return nil, nil
+	}
+	res := &Mix{Cycles: m.Cycles, Volume: float32(m.Volume)}
+	if m.Height != nil {
+		res.Height = float32(*m.Height)
+	}
+	if m.Rate != nil {
+		res.Rate = float32(*m.Rate)
+	}
+	return res, res.validate()
+}
+
+// mix runs m with the tip in the well at at, whose bottom is at Z bottom. The
+// plunger starts and ends ready.
+func (t *Transfer) mix(p *path, m *Mix, at Position, bottom float32) {
+	if m == nil {
+		return
+	}
+	if m.Height > 0 {
+		p.lift(bottom + m.Height)
+	}
+	pip := t.pipette()
+	for i := 0; i < m.Cycles; i++ {
+		p.add(pip.mix(m.Volume, m.Rate)...)
+	}
+	if p.cur.Z != at.Z {
+		p.lift(at.Z)
+	}
+}
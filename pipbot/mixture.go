@@ -0,0 +1,209 @@
+package pipbot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// volumeSlack is how far volumes may be out before a well counts as over
+// drawn or overflowing, to allow for rounding.
+const volumeSlack float32 = 1e-3
+
+// Mixture describes what is in a cell as the volume of each stock in it, in
+// µL. A stock is a cell that was filled before the run. Mixtures of mixtures,
+// dilutions and so on are kept as the stocks they were made from, so a well
+// filled from a dilution holds a share of the dilution's stocks.
+type Mixture struct {
+	Contents map[*Cell]float32
+}
+
+// Quantity is a value with its unit, such as a concentration of 10 mM or
+// 5 mg/mL. Deck files write it as the value followed by the unit.
+type Quantity struct {
+	Value float32
+	Unit  string
+}
+
+func (c Quantity) String() string {
+	return strings.TrimSpace(num(c.Value) + " " + c.Unit)
+}
+
+func (c Quantity) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+func (c *Quantity) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return unicode.IsLetter(r) && r != 'e' && r != 'E' || r == '%' || r == 'µ' || unicode.IsSpace(r)
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	v, err := strconv.ParseFloat(s[:i], 32)
+	if err != nil || v < 0 {
+		return fmt.Errorf("bad quantity %q, expected a value and a unit such as 10 mM", s)
+	}
+	c.Value, c.Unit = float32(v), strings.TrimSpace(s[i:])
+	return nil
+}
+
+// diluted is the concentration c comes to in a mixture that is fraction of
+// the stock.
+func (c Quantity) diluted(fraction float32) Quantity {
+	return Quantity{Value: c.Value * fraction, Unit: c.Unit}
+}
+
+// Fill makes c a stock holding vol µL.
+func (c *Cell) Fill(vol float32) error {
+	if vol < 0 {
+		return fmt.Errorf("cannot fill a well with %v µL", vol)
+	}
+	if c.MaxVolume > 0 && vol > c.MaxVolume+volumeSlack {
+		return fmt.Errorf("%w: %v µL in a %v µL well", ErrOverflow, vol, c.MaxVolume)
+	}
+	c.Content = &Mixture{Contents: map[*Cell]float32{c: vol}}
+	return nil
+}
+
+// Volume is how much liquid is in c, 0 if that is not known.
+func (c *Cell) Volume() float32 {
+	return c.Content.Volume()
+}
+
+// Volume is the total volume of m.
+func (m *Mixture) Volume() float32 {
+	if m == nil {
+		return 0
+	}
+	var res float32
+	for _, v := range m.Contents {
+		res += v
+	}
+	return res
+}
+
+// Fraction is the share of m that is stock, 0 to 1.
+func (m *Mixture) Fraction(stock *Cell) float32 {
+	total := m.Volume()
+	if total == 0 {
+		return 0
+	}
+	return m.Contents[stock] / total
+}
+
+func (m *Mixture) clone() *Mixture {
+	if m == nil {
+		return nil
+	}
+	res := &Mixture{Contents: make(map[*Cell]float32, len(m.Contents))}
+	for k, v := range m.Contents {
+		res.Contents[k] = v
+	}
+	return res
+}
+
+// take removes vol from m, the same share of every stock, and returns it.
+func (m *Mixture) take(vol float32) *Mixture {
+	res := &Mixture{Contents: make(map[*Cell]float32, len(m.Contents))}
+	total := m.Volume()
+	if total <= 0 {
+		return res
+	}
+	share := min32(vol/total, 1)
+	for k, v := range m.Contents {
+		res.Contents[k] = v * share
+		m.Contents[k] = v - v*share
+	}
+	return res
+}
+
+// add pours o into m.
+func (m *Mixture) add(o *Mixture) {
+	if m.Contents == nil {
+		m.Contents = make(map[*Cell]float32, len(o.Contents))
+	}
+	for k, v := range o.Contents {
+		m.Contents[k] += v
+	}
+}
+
+// contents keeps track of what is in cells. The nil contents works on the
+// cells themselves, as a run does; any other keeps its changes to one side,
+// so a plan can be checked without touching the deck.
+type contents map[*Cell]*Mixture
+
+func (cs contents) of(c *Cell) *Mixture {
+	if cs == nil {
+		return c.Content
+	}
+	if m, ok := cs[c]; ok {
+		return m
+	}
+	m := c.Content.clone()
+	cs[c] = m
+	return m
+}
+
+func (cs contents) set(c *Cell, m *Mixture) {
+	if cs == nil {
+		c.Content = m
+		return
+	}
+	cs[c] = m
+}
+
+// draw takes vol out of c and returns what was taken. A cell whose contents
+// are not known is a stock with as much as is drawn from it.
+func (cs contents) draw(c *Cell, vol float32) (*Mixture, error) {
+	m := cs.of(c)
+	if m == nil {
+		return &Mixture{Contents: map[*Cell]float32{c: vol}}, nil
+	}
+	if have := m.Volume(); vol > have+volumeSlack {
+		return nil, fmt.Errorf("%w: drawing %v µL from a well holding %v µL", ErrOverdraw, vol, have)
+	}
+	return m.take(vol), nil
+}
+
+// pour adds o to c.
+func (cs contents) pour(c *Cell, o *Mixture) error {
+	m := cs.of(c)
+	if m == nil {
+		m = &Mixture{}
+		cs.set(c, m)
+	}
+	if vol := m.Volume() + o.Volume(); c.MaxVolume > 0 && vol > c.MaxVolume+volumeSlack {
+		return fmt.Errorf("%w: %v µL in a %v µL well", ErrOverflow, vol, c.MaxVolume)
+	}
+	m.add(o)
+	return nil
+}
+
+// move draws vol from src and pours it into dest.
+func (cs contents) move(src, dest *Cell, vol float32) error {
+	m, err := cs.draw(src, vol)
+	if err != nil {
+		return err
+	}
+	return cs.pour(dest, m)
+}
+
+// checkVolumes follows actions through a copy of the deck's contents and
+// reports the first transfer that would over draw its source or overflow its
+// destination.
+func checkVolumes(actions []Action) error {
+	cs := contents{}
+	for i, a := range actions {
+		t, ok := a.(*Transfer)
+		if !ok || t.src == nil || t.dest == nil {
+			continue
+		}
+		if err := cs.move(t.src, t.dest, t.Volume); err != nil {
+			return fmt.Errorf("step %v: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package pipbot
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// DefaultTravelMargin is how far above the tallest obstacle on its way
+	// the tip crosses the deck, in mm.
+	DefaultTravelMargin float32 = 5
+	// DefaultTipLength is how far a tip on the stock pipette reaches below
+	// where it is held, in mm.
+	DefaultTipLength float32 = 63.5
+	// EjectX and EjectZ are where the pipette is pushed against the frame to
+	// knock its tip off.
+	EjectX float32 = 10
+	EjectZ float32 = 154
+)
+
+// Planner plans the moves of the tip around the deck. It crosses over
+// matrices high enough to clear all of them on the way, and refuses moves
+// that would run the tip into labware or out of the build volume.
+type Planner struct {
+	Layout *Layout
+	// Margin is how far above obstacles the tip travels, or
+	// DefaultTravelMargin if 0.
+	Margin float32
+	// TipLength is how far a tip reaches below where the pipette holds it,
+	// which is how much higher the tips in a rack stand once one is on.
+	TipLength float32
+}
+
+// Planner returns a planner for the deck of l with the pipette p.
+func (l *Layout) Planner(p *Pipette) *Planner {
+	return &Planner{Layout: l, Margin: l.TravelMargin, TipLength: p.tipLength()}
+}
+
+func (pl *Planner) margin() float32 {
+	if pl.Margin > 0 {
+		return pl.Margin
+	}
+	return DefaultTravelMargin
+}
+
+// top is the highest Z the tip touches m at: its Top, or its highest rim.
+// With a tip on the tips left in a rack stand TipLength higher.
+func (pl *Planner) top(m *Matrix, tip bool) float32 {
+	res := m.Top
+	if res == 0 {
+		res = m.Home.Z
+		for _, p := range m.corners() {
+			res = max32(res, p.Z)
+		}
+	}
+	if tip && m.Kind == Tip {
+		res += pl.TipLength
+	}
+	return res
+}
+
+// over is the height the tip crosses m at: the margin above its top. How
+// tall m stands above the rims it was taught at is known only from its Top
+// or Depth, so without either the tip keeps to the fixed heights of the stock
+// deck as well.
+func (pl *Planner) over(m *Matrix, tip bool) float32 {
+	res := pl.top(m, tip) + pl.margin()
+	if m.Top > 0 || m.Depth > 0 {
+		return res
+	}
+	switch {
+	case tip && m.Kind == Tip:
+		return max32(res, TipBoxClear)
+	case tip:
+		return max32(res, TipOnClear)
+	default:
+		return max32(res, TipOffClear)
+	}
+}
+
+// ceiling is the height that clears the whole deck, for when the tip could be
+// anywhere over it.
+func (pl *Planner) ceiling(tip bool) float32 {
+	var res float32
+	for _, m := range pl.Layout.Matrices {
+		res = max32(res, pl.over(m, tip))
+	}
+	return res
+}
+
+// clearance is the lowest Z the tip can cross from a to b at: above every
+// matrix the way passes over, other than one whose well it stays inside. When
+// a and b are the same it is the height that clears what is under a.
+func (pl *Planner) clearance(a, b Position, tip bool) float32 {
+	var res float32
+	across := a.X != b.X || a.Y != b.Y
+	for _, m := range pl.Layout.Matrices {
+		if !m.Footprint().crosses(a, b) {
+			continue
+		}
+		if across && !(tip && m.Kind == Tip) {
+			if c := m.wellAt(a); c != nil && c == m.wellAt(b) {
+				continue
+			}
+		}
+		res = max32(res, pl.over(m, tip))
+	}
+	return res
+}
+
+// check reports whether the tip can be at to: inside the build volume, and
+// either above every matrix under it or down one of its wells.
+func (pl *Planner) check(to Position, tip bool) error {
+	if err := to.check(); err != nil {
+		return err
+	}
+	for _, m := range pl.Layout.Matrices {
+		f := m.Footprint()
+		if to.Z >= pl.top(m, tip) || !f.contains(to) {
+			continue
+		}
+		if !(tip && m.Kind == Tip) {
+			if c := m.wellAt(to); c != nil && to.Z >= c.Z-c.depth() {
+				continue
+			}
+		}
+		return fmt.Errorf("%w: the tip would hit %q at X%v Y%v Z%v", ErrCollision, m.Name, to.X, to.Y, to.Z)
+	}
+	return nil
+}
+
+// travel gets the path ready to go to to: up first if it is too low to cross
+// over to it. The first move it cannot make safely is kept in p.err.
+func (p *path) travel(to Position) {
+	if p.err != nil {
+		return
+	}
+	if p.unsure {
+		// where the gantry is across the bed is not known, so clear all of it
+		p.unsure = false
+		if h := p.plan.ceiling(p.tip); h > p.cur.Z {
+			p.cur.Z = h
+			p.lines = append(p.lines, p.cur.Low())
+		}
+	}
+	hop := Position{X: to.X, Y: to.Y, Z: p.cur.Z}
+	if hop.X != p.cur.X || hop.Y != p.cur.Y {
+		if h := p.plan.clearance(p.cur, hop, p.tip); h > p.cur.Z {
+			p.cur.Z, hop.Z = h, h
+			p.lines = append(p.lines, p.cur.Low())
+		}
+		if p.err = p.plan.check(hop, p.tip); p.err != nil {
+			return
+		}
+	}
+	p.err = p.plan.check(to, p.tip)
+}
+
+// leave lifts the tip clear of whatever is under it, or to z without a
+// planner.
+func (p *path) leave(z float32) {
+	if p.plan == nil {
+		p.lift(z)
+		return
+	}
+	at := p.cur
+	if h := p.plan.clearance(at, at, p.tip); h > p.cur.Z {
+		p.lift(h)
+	}
+}
+
+// contains reports whether p is over r, edges included.
+func (r Rect) contains(p Position) bool {
+	return p.X >= r.MinX && p.X <= r.MaxX && p.Y >= r.MinY && p.Y <= r.MaxY
+}
+
+// crosses reports whether the straight line from a to b passes over r.
+func (r Rect) crosses(a, b Position) bool {
+	// clip the line to each pair of edges in turn (Liang-Barsky)
+	lo, hi := float32(0), float32(1)
+	for _, e := range [][3]float32{
+		{a.X, b.X - a.X, r.MinX}, {-a.X, a.X - b.X, -r.MaxX},
+		{a.Y, b.Y - a.Y, r.MinY}, {-a.Y, a.Y - b.Y, -r.MaxY},
+	} {
+		from, d, edge := e[0], e[1], e[2]
+		if d == 0 {
+			if from < edge {
+				return false
+			}
+			continue
+		}
+		t := (edge - from) / d
+		if d > 0 {
+			lo = max32(lo, t)
+		} else {
+			hi = min32(hi, t)
+		}
+	}
+	return lo <= hi
+}
+
+// wellAt returns the cell of m whose well p is in, or nil. Without a known
+// well shape a cell reaches half way to its neighbours.
+func (m *Matrix) wellAt(p Position) *Cell {
+	for _, row := range m.Cells {
+		for _, c := range row {
+			r := float64(min32(m.RowSpace, m.ColSpace)) / 2
+			if r == 0 {
+				r = float64(max32(m.RowSpace, m.ColSpace)) / 2
+			}
+			if c.Well != nil && c.Well.radius() > 0 {
+				r = c.Well.radius()
+			}
+			if math.Hypot(float64(p.X-c.X), float64(p.Y-c.Y)) <= r {
+				return c
+			}
+		}
+	}
+	return nil
+}
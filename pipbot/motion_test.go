@@ -0,0 +1,62 @@
+package pipbot
+
+import (
+	"testing"
+
+	"pipbot/graph/model"
+)
+
+// TestStockDeckCrossings runs a recipe on the stock deck, whose matrices have
+// no Top or Depth, and checks every move across the deck is at least as high
+// as the fixed safe heights.
+func TestStockDeckCrossings(t *testing.T) {
+	sim := NewSimulator(nil)
+	b := NewPipBotOn(sim)
+	if err := b.Init(); err != nil {
+		t.Fatal(err)
+	}
+	r := &Recipe{}
+	for _, dest := range []string{"A1", "A2", "H12"} {
+		for _, src := range []string{"A1", "C4"} {
+			r.Transfers = append(r.Transfers, &model.Transfer{
+				Source: &model.Node{Grid: "12", Position: src},
+				Dest:   &model.Node{Grid: "96", Position: dest},
+				Volume: 20,
+			})
+		}
+	}
+	actions, err := b.PlanRecipe(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = b.Run(actions); err != nil {
+		t.Fatal(err)
+	}
+	rack := b.Layout.Matrices[3]
+	box := rack.Footprint()
+	tip := false
+	var at Position
+	for _, s := range sim.Result().Timeline {
+		from := at
+		at = s.At
+		switch {
+		case box.contains(at) && at.Z <= rack.Home.Z:
+			tip = true
+		case at.X == EjectX && at.Z == EjectZ:
+			tip = false
+		}
+		if from.X == at.X && from.Y == at.Y {
+			continue
+		}
+		need := TipOffClear
+		if tip {
+			need = TipOnClear
+			if box.crosses(from, at) {
+				need = TipBoxClear
+			}
+		}
+		if z := min32(from.Z, at.Z); z < need {
+			t.Errorf("line %v: %q crosses at Z%v, below Z%v", s.Line, s.Command, z, need)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package pipbot
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrVolume is returned for volumes the pipette cannot handle.
+var ErrVolume = errors.New("volume out of range")
+
+// CurvePoint is a measured plunger travel, in mm, for a volume in µL.
+type CurvePoint struct {
+	Volume float32 `yaml:"volume" json:"volume"`
+	Travel float32 `yaml:"travel" json:"travel"`
+}
+
+// Curve converts volume to plunger travel. With Points it interpolates
+// linearly between them and extends the end segments; otherwise travel is
+// Slope*volume+Intercept.
+type Curve struct {
+	// Slope is in mm of travel per µL.
+	Slope     float32      `yaml:"slope" json:"slope"`
+	Intercept float32      `yaml:"intercept" json:"intercept"`
+	Points    []CurvePoint `yaml:"points,omitempty" json:"points,omitempty"`
+}
+
+// Travel is how far the plunger moves to draw vol.
+func (c Curve) Travel(vol float32) float32 {
+	if len(c.Points) < 2 {
+		return c.Slope*vol + c.Intercept
+	}
+	i := sort.Search(len(c.Points)-1, func(i int) bool { return c.Points[i+1].Volume >= vol })
+	if i == len(c.Points)-1 {
+		i--
+	}
+	a, b := c.Points[i], c.Points[i+1]
+	return a.Travel + (vol-a.Volume)*(b.Travel-a.Travel)/(b.Volume-a.Volume)
+}
+
+// air is the travel for a volume of air, which ignores the dead travel the
+// intercept accounts for.
+func (c Curve) air(vol float32) float32 {
+	return c.Travel(vol) - c.Travel(0)
+}
+
+func (c Curve) validate() error {
+	if len(c.Points) == 0 {
+		if c.Slope <= 0 {
+			return errors.New("curve slope must be positive")
+		}
+		return nil
+	}
+	if len(c.Points) < 2 {
+		return errors.New("a piecewise curve needs at least two points")
+	}
+	for i := 1; i < len(c.Points); i++ {
+		if c.Points[i].Volume <= c.Points[i-1].Volume || c.Points[i].Travel <= c.Points[i-1].Travel {
+			return errors.New("curve points must increase in both volume and travel")
+		}
+	}
+	return nil
+}
+
+// Pipette is a plunger pipette driven by the printer's E axis. Volumes are in
+// µL and flow rates in µL/s. E0 is the plunger pushed all the way down; it is
+// raised by the blowout volume before aspirating so that the last drop can be
+// pushed out afterwards.
+type Pipette struct {
+	Name         string  `yaml:"name" json:"name"`
+	MinVolume    float32 `yaml:"min_volume" json:"min_volume"`
+	MaxVolume    float32 `yaml:"max_volume" json:"max_volume"`
+	Curve        Curve   `yaml:"curve" json:"curve"`
+	AirGap       float32 `yaml:"air_gap,omitempty" json:"air_gap,omitempty"`
+	Blowout      float32 `yaml:"blowout,omitempty" json:"blowout,omitempty"`
+	AspirateRate float32 `yaml:"aspirate_rate" json:"aspirate_rate"`
+	DispenseRate float32 `yaml:"dispense_rate" json:"dispense_rate"`
+	// TipLength is how far a tip reaches below where the pipette holds it,
+	// in mm, or DefaultTipLength if 0.
+	TipLength float32 `yaml:"tip_length,omitempty" json:"tip_length,omitempty"`
+	// maxFeed is the fastest the plunger may move in mm/min, if set.
+	maxFeed float32
+}
+
+// DefaultFlowRate is the flow rate that moves the stock plunger at F500.
+const DefaultFlowRate float32 = 500.0 / 60 / DefaultSlope
+
+// StockPipette is the pipette the bot was built with.
+func StockPipette() *Pipette {
+	return &Pipette{
+		Name:         DefaultPipette,
+		MinVolume:    10,
+		MaxVolume:    200,
+		Curve:        Curve{Slope: DefaultSlope},
+		AspirateRate: DefaultFlowRate,
+		DispenseRate: DefaultFlowRate,
+	}
+}
+
+// Validate checks that the model is usable.
+func (p *Pipette) Validate() error {
+	if p.MinVolume < 0 || p.MaxVolume <= p.MinVolume {
+		return fmt.Errorf("pipette %q: volume range %v-%v µL is empty", p.Name, p.MinVolume, p.MaxVolume)
+	}
+	if p.AspirateRate <= 0 || p.DispenseRate <= 0 {
+		return fmt.Errorf("pipette %q: flow rates must be positive", p.Name)
+	}
+	if err := p.Curve.validate(); err != nil {
+		return fmt.Errorf("pipette %q: %w", p.Name, err)
+	}
+	if p.TipLength < 0 {
+		return fmt.Errorf("pipette %q: tip length cannot be negative", p.Name)
+	}
+	return nil
+}
+
+func (p *Pipette) tipLength() float32 {
+	if p.TipLength > 0 {
+		return p.TipLength
+	}
+	return DefaultTipLength
+}
+
+// Check reports whether vol can be drawn in one go.
+func (p *Pipette) Check(vol float32) error {
+	if vol < p.MinVolume || vol+p.AirGap > p.MaxVolume {
+		return fmt.Errorf("%w: %v µL on %v (%v-%v µL)", ErrVolume, vol, p.Name, p.MinVolume, p.MaxVolume)
+	}
+	return nil
+}
+
+// feed converts a flow rate to an E axis feed rate in mm/min.
+func (p *Pipette) feed(rate float32) float32 {
+	f := hundredths(p.Curve.air(rate) * 60)
+	if p.maxFeed > 0 && f > p.maxFeed {
+		return p.maxFeed
+	}
+	return f
+}
+
+// plunger is the G-code moving the plunger to e mm below E0.
+func (p *Pipette) plunger(rate, e float32) string {
+	return fmt.Sprintf("G1 F%v E%v", p.feed(rate), 0-hundredths(e))
+}
+
+// ready raises the plunger by the blowout volume. It is a no-op without one.
+func (p *Pipette) ready() []string {
+	if p.Blowout <= 0 {
+		return nil
+	}
+	return []string{p.plunger(p.AspirateRate, p.Curve.air(p.Blowout))}
+}
+
+// aspirate draws vol from the ready position.
+func (p *Pipette) aspirate(vol float32) string {
+	return p.plunger(p.AspirateRate, p.Curve.air(p.Blowout)+p.Curve.Travel(vol))
+}
+
+// follow draws vol from the ready position while the tip moves down dz to z,
+// taking as long as aspirate would.
+func (p *Pipette) follow(vol, z, dz float32) string {
+	travel := p.Curve.Travel(vol)
+	minutes := travel / p.feed(p.AspirateRate)
+	return fmt.Sprintf("G1 F%v Z%v E%v", hundredths(dz/minutes), hundredths(z), 0-hundredths(p.Curve.air(p.Blowout)+travel))
+}
+
+// airGap draws the air gap on top of vol once the tip is out of the liquid.
+func (p *Pipette) airGap(vol float32) []string {
+	if p.AirGap <= 0 {
+		return nil
+	}
+	return []string{p.plunger(p.AspirateRate, p.Curve.air(p.Blowout)+p.Curve.Travel(vol)+p.Curve.air(p.AirGap))}
+}
+
+// mix draws vol from the ready position and pushes it back out, at rate both
+// ways or at the pipette's own rates if rate is 0.
+func (p *Pipette) mix(vol, rate float32) []string {
+	up, down := p.AspirateRate, p.DispenseRate
+	if rate > 0 {
+		up, down = rate, rate
+	}
+	return []string{p.plunger(up, p.Curve.air(p.Blowout)+p.Curve.Travel(vol)), p.plunger(down, p.Curve.air(p.Blowout))}
+}
+
+// dispense pushes the plunger back to the ready position.
+func (p *Pipette) dispense() string {
+	return p.plunger(p.DispenseRate, p.Curve.air(p.Blowout))
+}
+
+// blowout pushes the plunger all the way down to E0.
+func (p *Pipette) blowout() string {
+	return p.plunger(p.DispenseRate, 0)
+}
@@ -0,0 +1,216 @@
+package pipbot
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"pipbot/graph/model"
+)
+
+// LegendEntry is what a label in a plate map stands for: where to draw from
+// and how much to put in each well. An empty SourceLabware is the deck's
+// source matrix.
+type LegendEntry struct {
+	SourceLabware string
+	SourceWell    string
+	Volume        float64
+}
+
+// DefaultLegend is used for plate maps that bring no legend of their own. It
+// is the colors the bot was first used with, drawn from the first row of the
+// source matrix.
+var DefaultLegend = map[string]LegendEntry{
+	"Blue":   {SourceWell: "A1", Volume: 100},
+	"Red":    {SourceWell: "A2", Volume: 100},
+	"Orange": {SourceWell: "A3", Volume: 100},
+}
+
+// LoadPlateMap reads a plate map into a Recipe. A plate map is a CSV picture
+// of one or more destination plates in which each cell holds a label, a label
+// and a volume such as "Blue:50", or nothing:
+//
+//	plate,96
+//	Blue,Blue,Red:50
+//	,Red,Red
+//	plate,96b
+//	Orange,,Blue
+//	legend
+//	label,source_labware,source_well,volume
+//	Blue,12,A1,100
+//	Red,12,A2,100
+//	Orange,12,A3,100
+//
+// A "plate" row starts the picture of the matrix it names; rows before the
+// first one, or a plate row without a name, picture the deck's destination
+// matrix. The picture can be any size that fits the matrix. Labels are
+// defined in a "legend" section at the end, in legendFile, or both; a map with
+// neither uses DefaultLegend. A volume in a cell wins over the legend's.
+func LoadPlateMap(file, legendFile string) (*Recipe, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	records, err := readPlateCSV(file, b)
+	if err != nil {
+		return nil, err
+	}
+
+	legend := make(map[string]LegendEntry)
+	if legendFile != "" {
+		lb, err := os.ReadFile(legendFile)
+		if err != nil {
+			return nil, err
+		}
+		lrecs, err := readPlateCSV(legendFile, lb)
+		if err != nil {
+			return nil, err
+		}
+		if err = readLegend(legendFile, lrecs, legend); err != nil {
+			return nil, err
+		}
+	}
+	for i, rec := range records {
+		if isKeyword(rec.cells, "legend") {
+			if err = readLegend(file, records[i+1:], legend); err != nil {
+				return nil, err
+			}
+			records = records[:i]
+			break
+		}
+	}
+	if len(legend) == 0 {
+		legend = DefaultLegend
+	}
+
+	res := &Recipe{File: file}
+	plate, row := "", 0
+	for _, rec := range records {
+		if isKeyword(rec.cells, "plate") {
+			if len(rec.cells) > 1 {
+				plate = strings.TrimSpace(rec.cells[1])
+			} else {
+				plate = ""
+			}
+			row = 0
+			continue
+		}
+		for col, cell := range rec.cells {
+			label, vol, err := parseLabel(cell)
+			if err != nil {
+				return nil, &PlanError{File: file, Line: rec.line, Column: col + 1, Err: err}
+			}
+			if label == "" {
+				continue
+			}
+			e, ok := legend[label]
+			if !ok {
+				return nil, &PlanError{File: file, Line: rec.line, Column: col + 1, Err: fmt.Errorf("label %q is not in the legend", label)}
+			}
+			if vol == 0 {
+				vol = e.Volume
+			}
+			if vol <= 0 {
+				return nil, &PlanError{File: file, Line: rec.line, Column: col + 1, Err: fmt.Errorf("no volume for label %q", label)}
+			}
+			name := label
+			res.Transfers = append(res.Transfers, &model.Transfer{
+				ID:     strconv.Itoa(len(res.Transfers) + 1),
+				Name:   &name,
+				Source: &model.Node{Grid: e.SourceLabware, Position: e.SourceWell, Aspirate: true},
+				Dest:   &model.Node{Grid: plate, Position: WellName(row, col)},
+				Volume: vol,
+			})
+			res.at = append(res.at, filePos{line: rec.line, col: col + 1})
+		}
+		row++
+	}
+	return res, nil
+}
+
+// plateRecord is a row of a plate map CSV and the line it is on.
+type plateRecord struct {
+	line  int
+	cells []string
+}
+
+// readPlateCSV reads the rows of a plate map or legend. Rows may have any
+// number of cells.
+func readPlateCSV(file string, b []byte) ([]plateRecord, error) {
+	r := csv.NewReader(bytes.NewReader(b))
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	var res []plateRecord
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return res, nil
+		}
+		if err != nil {
+			var pe *csv.ParseError
+			if errors.As(err, &pe) {
+				return nil, &PlanError{File: file, Line: pe.Line, Column: pe.Column, Err: pe.Err}
+			}
+			return nil, &PlanError{File: file, Err: err}
+		}
+		line, _ := r.FieldPos(0)
+		res = append(res, plateRecord{line: line, cells: rec})
+	}
+}
+
+// isKeyword reports whether cells is a section row starting with word.
+func isKeyword(cells []string, word string) bool {
+	return len(cells) > 0 && strings.EqualFold(strings.TrimSpace(cells[0]), word)
+}
+
+// readLegend adds the entries in recs to legend. Each is label,
+// source_labware, source_well and, optionally, volume; a header row starting
+// with "label" is skipped.
+func readLegend(file string, recs []plateRecord, legend map[string]LegendEntry) error {
+	for _, rec := range recs {
+		c := rec.cells
+		if isKeyword(c, "label") || isKeyword(c, "legend") || len(c) == 1 && strings.TrimSpace(c[0]) == "" {
+			continue
+		}
+		if len(c) < 3 {
+			return &PlanError{File: file, Line: rec.line, Err: errors.New("expected label,source_labware,source_well,volume")}
+		}
+		e := LegendEntry{SourceLabware: strings.TrimSpace(c[1]), SourceWell: strings.ToUpper(strings.TrimSpace(c[2]))}
+		if _, _, err := ParseWell(e.SourceWell); err != nil {
+			return &PlanError{File: file, Line: rec.line, Column: 3, Err: err}
+		}
+		if len(c) > 3 && strings.TrimSpace(c[3]) != "" {
+			v, err := strconv.ParseFloat(strings.TrimSpace(c[3]), 64)
+			if err != nil || v <= 0 {
+				return &PlanError{File: file, Line: rec.line, Column: 4, Err: fmt.Errorf("bad volume %q", c[3])}
+			}
+			e.Volume = v
+		}
+		label := strings.TrimSpace(c[0])
+		if _, ok := legend[label]; ok {
+			return &PlanError{File: file, Line: rec.line, Err: fmt.Errorf("label %q is in the legend twice", label)}
+		}
+		legend[label] = e
+	}
+	return nil
+}
+
+// parseLabel splits a plate map cell into its label and volume, which is 0 if
+// the cell does not give one.
+func parseLabel(cell string) (string, float64, error) {
+	label, vol, ok := strings.Cut(strings.TrimSpace(cell), ":")
+	label = strings.TrimSpace(label)
+	if !ok {
+		return label, 0, nil
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(vol), 64)
+	if err != nil || v <= 0 {
+		return "", 0, fmt.Errorf("bad volume in %q", cell)
+	}
+	return label, v, nil
+}
@@ -0,0 +1,100 @@
+package pipbot
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadPlateMap(t *testing.T) {
+	for _, tc := range []struct {
+		name, content, legend string
+		want                  []summary
+	}{
+		{
+			name: "plates and legend",
+			content: `plate,96
+Blue,Blue,Red:50
+,Red,Red
+plate,96b
+Orange,,Blue
+legend
+label,source_labware,source_well,volume
+Blue,12,A1,100
+Red,12,a2,100
+Orange,12,A3,100
+`,
+			want: []summary{
+				{Src: "12:A1", Dest: "96:A1", Volume: 100},
+				{Src: "12:A1", Dest: "96:A2", Volume: 100},
+				{Src: "12:A2", Dest: "96:A3", Volume: 50},
+				{Src: "12:A2", Dest: "96:B2", Volume: 100},
+				{Src: "12:A2", Dest: "96:B3", Volume: 100},
+				{Src: "12:A3", Dest: "96b:A1", Volume: 100},
+				{Src: "12:A1", Dest: "96b:A3", Volume: 100},
+			},
+		},
+		{
+			name:    "default legend",
+			content: "# rows before a plate row are the destination matrix\nBlue,, Red : 20\n\nOrange\n",
+			want: []summary{
+				{Src: ":A1", Dest: ":A1", Volume: 100},
+				{Src: ":A2", Dest: ":A3", Volume: 20},
+				{Src: ":A3", Dest: ":B1", Volume: 100},
+			},
+		},
+		{
+			name:    "legend file and section",
+			content: "plate,deep\nbuffer,dye:5\nlegend\ndye,tubes,B1,10\n",
+			legend:  "label,source_labware,source_well,volume\nbuffer,reservoir,A1,200\n",
+			want: []summary{
+				{Src: "reservoir:A1", Dest: "deep:A1", Volume: 200},
+				{Src: "tubes:B1", Dest: "deep:A2", Volume: 5},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var legend string
+			if tc.legend != "" {
+				legend = writeFile(t, "legend.csv", tc.legend)
+			}
+			r, err := LoadPlateMap(writeFile(t, "map.csv", tc.content), legend)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := summarize(r.Transfers); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v\nwant %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadPlateMapErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name, content string
+		// want is the error without the directory of the file
+		want string
+	}{
+		{name: "label", content: "Blue,Green\n", want: `map.csv:1:2: label "Green" is not in the legend`},
+		{name: "cell volume", content: "plate,96\n,,Blue:lots\n", want: `map.csv:2:3: bad volume in "Blue:lots"`},
+		{name: "no volume", content: "Blue\nlegend\nBlue,12,A1\n", want: `map.csv:1:1: no volume for label "Blue"`},
+		{name: "short legend row", content: "Blue\nlegend\nBlue,12\n", want: "map.csv:3: expected label,source_labware,source_well,volume"},
+		{name: "legend well", content: "Blue\nlegend\nBlue,12,99,10\n", want: `map.csv:3:3: bad well name "99"`},
+		{name: "legend volume", content: "Blue\nlegend\nBlue,12,A1,-10\n", want: `map.csv:3:4: bad volume "-10"`},
+		{name: "legend twice", content: "Blue\nlegend\nBlue,12,A1,10\nBlue,12,A2,10\n", want: `map.csv:4: label "Blue" is in the legend twice`},
+		{name: "quote", content: "Blue,\"Red\n", want: "map.csv:1:"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := LoadPlateMap(writeFile(t, "map.csv", tc.content), "")
+			var pe *PlanError
+			if !errors.As(err, &pe) {
+				t.Fatalf("got %v, want a *PlanError", err)
+			}
+			pe.File = filepath.Base(pe.File)
+			if got := pe.Error(); len(got) < len(tc.want) || got[:len(tc.want)] != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,434 @@
+package pipbot
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"pipbot/graph/model"
+)
+
+// Protocol generators build recipes for common jobs. The recipes they return
+// are planned and run like any other, or written out with WriteCSV to be
+// checked and edited first.
+
+// Dilution is a serial dilution along a row or column of a plate. The wells
+// after Start are filled with Volume of diluent, then each well in turn passes
+// Volume/(Factor-1) on to the next, which is mixed, so every well is Factor
+// times weaker than the one before. All but the last well end up holding
+// Volume; the last keeps what it was passed as well.
+type Dilution struct {
+	// Labware and Start are the plate and the well holding the top
+	// concentration.
+	Labware string
+	Start   string
+	// Steps is the number of wells diluted after Start.
+	Steps int
+	// Down runs the series down a column instead of along a row.
+	Down   bool
+	Factor float64
+	Volume float64
+	// DiluentLabware and DiluentWell are where the diluent is drawn from.
+	DiluentLabware string
+	DiluentWell    string
+	// SampleLabware and SampleWell, when given, are a stock to fill Start
+	// from. Otherwise Start must already hold Volume plus what it passes on.
+	SampleLabware string
+	SampleWell    string
+	// MixCycles is how many times each well is mixed after it is passed the
+	// one before, with MixVolume each time. MixVolume defaults to what was
+	// passed.
+	MixCycles int
+	MixVolume float64
+}
+
+// SerialDilution builds the recipe for d.
+func SerialDilution(d Dilution) (*Recipe, error) {
+	row, col, err := ParseWell(d.Start)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case d.Steps < 1:
+		return nil, fmt.Errorf("a dilution needs at least one step, got %v", d.Steps)
+	case d.Factor <= 1:
+		return nil, fmt.Errorf("dilution factor %v must be more than 1", d.Factor)
+	case d.Volume <= 0:
+		return nil, fmt.Errorf("volume %v must be positive", d.Volume)
+	case d.MixCycles < 0 || d.MixVolume < 0:
+		return nil, errors.New("mixing cycles and volume cannot be negative")
+	}
+	well := func(i int) string {
+		if d.Down {
+			return WellName(row+i, col)
+		}
+		return WellName(row, col+i)
+	}
+	pass := round2(d.Volume / (d.Factor - 1))
+	var mix *Mix
+	if d.MixCycles > 0 {
+		mix = &Mix{Cycles: d.MixCycles, Volume: float32(d.MixVolume)}
+		if mix.Volume == 0 {
+			mix.Volume = float32(pass)
+		}
+	}
+
+	r := &Recipe{File: "serial dilution"}
+	if d.SampleLabware != "" || d.SampleWell != "" {
+		if err = r.addRow(d.SampleLabware, d.SampleWell, d.Labware, well(0), round2(d.Volume+pass), "sample"); err != nil {
+			return nil, err
+		}
+	}
+	for i := 1; i <= d.Steps; i++ {
+		if err = r.addRow(d.DiluentLabware, d.DiluentWell, d.Labware, well(i), d.Volume, "diluent"); err != nil {
+			return nil, err
+		}
+	}
+	for i := 0; i < d.Steps; i++ {
+		err = r.add(&recipeRow{
+			SourceLabware: d.Labware, SourceWell: well(i),
+			DestLabware: d.Labware, DestWell: well(i + 1),
+			Volume: pass, MixAfter: mix, Name: fmt.Sprintf("step %v", i+1),
+		}, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Stamp copies vol from every well of the matrix src into the same well of
+// dest, which must be at least as big.
+func Stamp(l *Layout, src, dest string, vol float64) (*Recipe, error) {
+	s, err := l.Matrix(src)
+	if err != nil {
+		return nil, err
+	}
+	d, err := l.Matrix(dest)
+	if err != nil {
+		return nil, err
+	}
+	if d.Rows < s.Rows || d.Columns < s.Columns {
+		return nil, fmt.Errorf("%v (%vx%v) does not fit in %v (%vx%v)", s.Name, s.Rows, s.Columns, d.Name, d.Rows, d.Columns)
+	}
+	r := &Recipe{File: "stamp"}
+	for row := 0; row < s.Rows; row++ {
+		for col := 0; col < s.Columns; col++ {
+			w := WellName(row, col)
+			if err = r.addRow(src, w, dest, w, vol, ""); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return r, nil
+}
+
+// Concentration is the measured concentration of a sample well, in any unit
+// as long as it matches the target's.
+type Concentration struct {
+	Well  string
+	Value float64
+	// DestWell is where the normalized sample goes; the same well if empty.
+	DestWell string
+	line     int
+}
+
+// LoadConcentrations reads a CSV with a header naming the columns well and
+// concentration, and optionally dest_well.
+func LoadConcentrations(file string) ([]Concentration, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var res []Concentration
+	err = readTable(file, b, []string{"well", "concentration"}, []string{"dest_well"}, func(line int, get func(string) string) error {
+		c := Concentration{Well: strings.ToUpper(get("well")), DestWell: strings.ToUpper(get("dest_well")), line: line}
+		if _, _, err := ParseWell(c.Well); err != nil {
+			return err
+		}
+		if c.DestWell != "" {
+			if _, _, err := ParseWell(c.DestWell); err != nil {
+				return err
+			}
+		}
+		v, err := strconv.ParseFloat(get("concentration"), 64)
+		if err != nil || v < 0 {
+			return fmt.Errorf("bad concentration %q", get("concentration"))
+		}
+		c.Value = v
+		res = append(res, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Normalization brings samples down to a common concentration. Each
+// destination well gets Volume in all: enough sample to hold Target, made up
+// with diluent.
+type Normalization struct {
+	Samples        []Concentration
+	SampleLabware  string
+	DestLabware    string
+	DiluentLabware string
+	DiluentWell    string
+	Target         float64
+	Volume         float64
+	// File is where Samples were read from, for errors.
+	File string
+}
+
+// Normalize builds the recipe for n. The diluent goes in first so one tip
+// can do all of it.
+func Normalize(n Normalization) (*Recipe, error) {
+	if n.Target <= 0 || n.Volume <= 0 {
+		return nil, errors.New("target concentration and volume must be positive")
+	}
+	file := n.File
+	if file == "" {
+		file = "normalization"
+	}
+	r := &Recipe{File: file}
+	type split struct {
+		c               Concentration
+		sample, diluent float64
+	}
+	splits := make([]split, len(n.Samples))
+	for i, c := range n.Samples {
+		if c.Value < n.Target {
+			return nil, &PlanError{File: file, Line: c.line, Err: fmt.Errorf("%v at %v is below the target of %v", c.Well, c.Value, n.Target)}
+		}
+		if c.DestWell == "" {
+			c.DestWell = c.Well
+		}
+		sample := round2(n.Volume * n.Target / c.Value)
+		splits[i] = split{c: c, sample: sample, diluent: round2(n.Volume - sample)}
+	}
+	for _, s := range splits {
+		if s.diluent <= 0 {
+			continue
+		}
+		if err := r.addRow(n.DiluentLabware, n.DiluentWell, n.DestLabware, s.c.DestWell, s.diluent, "diluent"); err != nil {
+			return nil, err
+		}
+		r.at[len(r.at)-1].line = s.c.line
+	}
+	for _, s := range splits {
+		if err := r.addRow(n.SampleLabware, s.c.Well, n.DestLabware, s.c.DestWell, s.sample, "sample"); err != nil {
+			return nil, err
+		}
+		r.at[len(r.at)-1].line = s.c.line
+	}
+	return r, nil
+}
+
+// Pick is a well to cherry-pick, and how much of it.
+type Pick struct {
+	Labware string
+	Well    string
+	Volume  float64
+}
+
+// LoadPicks reads a CSV with a header naming the columns source_labware and
+// source_well, and optionally volume.
+func LoadPicks(file string) ([]Pick, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var res []Pick
+	err = readTable(file, b, []string{"source_labware", "source_well"}, []string{"volume"}, func(line int, get func(string) string) error {
+		p := Pick{Labware: get("source_labware"), Well: strings.ToUpper(get("source_well"))}
+		if _, _, err := ParseWell(p.Well); err != nil {
+			return err
+		}
+		if v := get("volume"); v != "" {
+			if p.Volume, err = strconv.ParseFloat(v, 64); err != nil || p.Volume <= 0 {
+				return fmt.Errorf("bad volume %q", v)
+			}
+		}
+		res = append(res, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// CherryPick moves each of picks into the next free well of the matrix dest,
+// starting at start and running along rows, or down columns if byColumn is
+// set. Picks without a volume of their own take vol.
+func CherryPick(l *Layout, picks []Pick, dest, start string, byColumn bool, vol float64) (*Recipe, error) {
+	d, err := l.Matrix(dest)
+	if err != nil {
+		return nil, err
+	}
+	row, col, err := ParseWell(start)
+	if err != nil {
+		return nil, err
+	}
+	i := row*d.Columns + col
+	if byColumn {
+		i = col*d.Rows + row
+	}
+	r := &Recipe{File: "cherry-pick"}
+	for n, p := range picks {
+		if i >= d.Rows*d.Columns {
+			return nil, fmt.Errorf("%w: %v is full after %v of %v picks", ErrOutOfBounds, d.Name, n, len(picks))
+		}
+		w := WellName(i/d.Columns, i%d.Columns)
+		if byColumn {
+			w = WellName(i%d.Rows, i/d.Rows)
+		}
+		v := p.Volume
+		if v == 0 {
+			v = vol
+		}
+		if err = r.addRow(p.Labware, p.Well, dest, w, v, ""); err != nil {
+			return nil, err
+		}
+		i++
+	}
+	return r, nil
+}
+
+// addRow appends a transfer of vol from srcLabware:srcWell to
+// destLabware:destWell.
+func (r *Recipe) addRow(srcLabware, srcWell, destLabware, destWell string, vol float64, name string) error {
+	return r.add(&recipeRow{
+		SourceLabware: srcLabware, SourceWell: srcWell,
+		DestLabware: destLabware, DestWell: destWell,
+		Volume: vol, Name: name,
+	}, 0)
+}
+
+// WriteCSV writes r as a CSV recipe that LoadRecipe reads back.
+func (r *Recipe) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"source_labware", "source_well", "dest_labware", "dest_well", "volume",
+		"tip_policy", "liquid_class", "mix_before", "mix_after", "name", "group", "sample_id"}); err != nil {
+		return err
+	}
+	str := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	mix := func(m *model.Mix) string {
+		res, err := mixFrom(m)
+		if res == nil || err != nil {
+			return ""
+		}
+		return res.String()
+	}
+	for _, t := range r.Transfers {
+		rec := []string{
+			t.Source.Grid, t.Source.Position, t.Dest.Grid, t.Dest.Position,
+			strconv.FormatFloat(t.Volume, 'f', -1, 64), str(t.TipPolicy), str(t.LiquidClass), mix(t.MixBefore), mix(t.MixAfter),
+			str(t.Name), str(t.Group), t.SampleID,
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// colError is an error in one column of a row, which readTable reports at
+// that column.
+type colError struct {
+	col string
+	err error
+}
+
+func (e *colError) Error() string {
+	return fmt.Sprintf("%v: %v", e.col, e.err)
+}
+
+// readTable reads a CSV with a header row that has the columns in need and
+// may have those in may, calling row with each line after it. Errors are
+// *PlanError: those row returns are reported on its line, and at the column
+// of a *colError.
+func readTable(file string, b []byte, need, may []string, row func(line int, get func(col string) string) error) error {
+	r := csv.NewReader(bytes.NewReader(b))
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+	var cols map[string]int
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var pe *csv.ParseError
+			if errors.As(err, &pe) {
+				return &PlanError{File: file, Line: pe.Line, Column: pe.Column, Err: pe.Err}
+			}
+			return &PlanError{File: file, Err: err}
+		}
+		line, _ := r.FieldPos(0)
+		if cols == nil {
+			cols = make(map[string]int)
+			for i, h := range rec {
+				cols[strings.ToLower(strings.TrimSpace(h))] = i
+			}
+			for _, c := range need {
+				if _, ok := cols[c]; !ok {
+					return &PlanError{File: file, Line: line, Err: fmt.Errorf("missing column %q", c)}
+				}
+			}
+			for h := range cols {
+				if !contains(need, h) && !contains(may, h) {
+					return &PlanError{File: file, Line: line, Err: fmt.Errorf("unknown column %q", h)}
+				}
+			}
+			continue
+		}
+		get := func(c string) string {
+			if i, ok := cols[c]; ok {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+		if err = row(line, get); err != nil {
+			var pe *PlanError
+			var ce *colError
+			switch {
+			case errors.As(err, &pe):
+				return pe
+			case errors.As(err, &ce):
+				return &PlanError{File: file, Line: line, Column: cols[ce.col] + 1, Err: ce.err}
+			}
+			return &PlanError{File: file, Line: line, Err: err}
+		}
+	}
+	if cols == nil {
+		return &PlanError{File: file, Err: errors.New("file is empty")}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// round2 rounds v to 0.01 µL, finer than any pipette can measure.
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,201 @@
+package pipbot
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"pipbot/graph/model"
+)
+
+func TestSerialDilution(t *testing.T) {
+	mix := &model.Mix{Cycles: 3, Volume: 100}
+	for _, tc := range []struct {
+		name string
+		d    Dilution
+		want []summary
+	}{
+		{
+			name: "1:2 over 8 wells",
+			d: Dilution{Labware: "plate", Start: "A1", Steps: 7, Factor: 2, Volume: 100,
+				DiluentLabware: "res", DiluentWell: "A1", SampleLabware: "stock", SampleWell: "B2", MixCycles: 3},
+			want: []summary{
+				{Src: "stock:B2", Dest: "plate:A1", Volume: 200},
+				{Src: "res:A1", Dest: "plate:A2", Volume: 100},
+				{Src: "res:A1", Dest: "plate:A3", Volume: 100},
+				{Src: "res:A1", Dest: "plate:A4", Volume: 100},
+				{Src: "res:A1", Dest: "plate:A5", Volume: 100},
+				{Src: "res:A1", Dest: "plate:A6", Volume: 100},
+				{Src: "res:A1", Dest: "plate:A7", Volume: 100},
+				{Src: "res:A1", Dest: "plate:A8", Volume: 100},
+				{Src: "plate:A1", Dest: "plate:A2", Volume: 100, MixAfter: mix},
+				{Src: "plate:A2", Dest: "plate:A3", Volume: 100, MixAfter: mix},
+				{Src: "plate:A3", Dest: "plate:A4", Volume: 100, MixAfter: mix},
+				{Src: "plate:A4", Dest: "plate:A5", Volume: 100, MixAfter: mix},
+				{Src: "plate:A5", Dest: "plate:A6", Volume: 100, MixAfter: mix},
+				{Src: "plate:A6", Dest: "plate:A7", Volume: 100, MixAfter: mix},
+				{Src: "plate:A7", Dest: "plate:A8", Volume: 100, MixAfter: mix},
+			},
+		},
+		{
+			name: "1:10 down a column from a filled well",
+			d:    Dilution{Labware: "plate", Start: "B3", Steps: 2, Down: true, Factor: 10, Volume: 90, DiluentLabware: "res", DiluentWell: "A1"},
+			want: []summary{
+				{Src: "res:A1", Dest: "plate:C3", Volume: 90},
+				{Src: "res:A1", Dest: "plate:D3", Volume: 90},
+				{Src: "plate:B3", Dest: "plate:C3", Volume: 10},
+				{Src: "plate:C3", Dest: "plate:D3", Volume: 10},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := SerialDilution(tc.d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := summarize(r.Transfers); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v\nwant %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSerialDilutionErrors(t *testing.T) {
+	ok := Dilution{Labware: "plate", Start: "A1", Steps: 3, Factor: 2, Volume: 100, DiluentLabware: "res", DiluentWell: "A1"}
+	for name, change := range map[string]func(d *Dilution){
+		"no steps":        func(d *Dilution) { d.Steps = 0 },
+		"factor of 1":     func(d *Dilution) { d.Factor = 1 },
+		"no volume":       func(d *Dilution) { d.Volume = 0 },
+		"bad start":       func(d *Dilution) { d.Start = "1A" },
+		"negative mixing": func(d *Dilution) { d.MixCycles = -1 },
+	} {
+		t.Run(name, func(t *testing.T) {
+			d := ok
+			change(&d)
+			if _, err := SerialDilution(d); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
+
+// protocolDeck is a deck with a 2x2 source, a 2x3 destination and a
+// reservoir.
+func protocolDeck() *Layout {
+	return &Layout{Matrices: []*Matrix{
+		NewMatrix(Standard, "src", Position{X: 20, Y: 20, Z: 70}, 9, 9, 2, 2),
+		NewMatrix(Standard, "dest", Position{X: 80, Y: 20, Z: 70}, 9, 9, 2, 3),
+		NewMatrix(Stock, "res", Position{X: 150, Y: 20, Z: 70}, 9, 9, 1, 1),
+	}}
+}
+
+func TestStamp(t *testing.T) {
+	l := protocolDeck()
+	r, err := Stamp(l, "src", "dest", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []summary{
+		{Src: "src:A1", Dest: "dest:A1", Volume: 20},
+		{Src: "src:A2", Dest: "dest:A2", Volume: 20},
+		{Src: "src:B1", Dest: "dest:B1", Volume: 20},
+		{Src: "src:B2", Dest: "dest:B2", Volume: 20},
+	}
+	if got := summarize(r.Transfers); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+	if _, err = Stamp(l, "dest", "src", 20); err == nil {
+		t.Error("stamped a 2x3 matrix into a 2x2 one")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	n := Normalization{
+		Samples: []Concentration{
+			{Well: "A1", Value: 100, line: 2},
+			{Well: "A2", Value: 50, DestWell: "B1", line: 3},
+			{Well: "B2", Value: 25, line: 4},
+		},
+		SampleLabware: "src", DestLabware: "dest", DiluentLabware: "res", DiluentWell: "A1",
+		Target: 25, Volume: 40, File: "conc.csv",
+	}
+	r, err := Normalize(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the diluent goes in first, and none into a sample already at the target
+	want := []summary{
+		{Src: "res:A1", Dest: "dest:A1", Volume: 30},
+		{Src: "res:A1", Dest: "dest:B1", Volume: 20},
+		{Src: "src:A1", Dest: "dest:A1", Volume: 10},
+		{Src: "src:A2", Dest: "dest:B1", Volume: 20},
+		{Src: "src:B2", Dest: "dest:B2", Volume: 40},
+	}
+	if got := summarize(r.Transfers); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	n.Target = 30
+	_, err = Normalize(n)
+	var pe *PlanError
+	if !errors.As(err, &pe) || pe.Line != 4 {
+		t.Errorf("got %v, want an error on line 4 for the weak sample", err)
+	}
+}
+
+func TestCherryPick(t *testing.T) {
+	l := protocolDeck()
+	picks := []Pick{{Labware: "src", Well: "B2"}, {Labware: "src", Well: "A1", Volume: 5}, {Labware: "src", Well: "A2"}}
+	for _, tc := range []struct {
+		name     string
+		start    string
+		byColumn bool
+		dests    []string
+	}{
+		{name: "along rows", start: "A2", dests: []string{"dest:A2", "dest:A3", "dest:B1"}},
+		{name: "down columns", start: "B1", byColumn: true, dests: []string{"dest:B1", "dest:A2", "dest:B2"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := CherryPick(l, picks, "dest", tc.start, tc.byColumn, 15)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []summary{
+				{Src: "src:B2", Dest: tc.dests[0], Volume: 15},
+				{Src: "src:A1", Dest: tc.dests[1], Volume: 5},
+				{Src: "src:A2", Dest: tc.dests[2], Volume: 15},
+			}
+			if got := summarize(r.Transfers); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v\nwant %+v", got, want)
+			}
+		})
+	}
+	if _, err := CherryPick(l, picks, "dest", "B2", false, 15); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("got %v, want the destination to be full", err)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	r, err := SerialDilution(Dilution{Labware: "plate", Start: "A1", Steps: 2, Factor: 3, Volume: 50,
+		DiluentLabware: "res", DiluentWell: "A1", MixCycles: 2, MixVolume: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = r.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	back, err := readRecipeCSV("dilution.csv", buf.Bytes())
+	if err != nil {
+		t.Fatalf("%v in\n%s", err, buf.Bytes())
+	}
+	if got, want := summarize(back.Transfers), summarize(r.Transfers); !reflect.DeepEqual(got, want) {
+		t.Errorf("read back %+v\nwant %+v", got, want)
+	}
+	for i, tr := range back.Transfers {
+		if !reflect.DeepEqual(tr.Name, r.Transfers[i].Name) {
+			t.Errorf("transfer %v is named %v, want %v", i, *tr.Name, *r.Transfers[i].Name)
+		}
+	}
+}
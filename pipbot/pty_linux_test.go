@@ -0,0 +1,53 @@
+//go:build linux
+
+package pipbot
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFakePrinterRoundTrip(t *testing.T) {
+	f, err := NewFakePrinter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.Reply = func(line string) []string {
+		return []string{"echo:" + line, "ok"}
+	}
+	s, err := NewSerial(f.Port(), Baud)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	in := bufio.NewScanner(s)
+	for _, cmd := range []string{"G28", "M114"} {
+		if _, err := s.Write([]byte(cmd + "\n")); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for len(got) < 2 && in.Scan() {
+			got = append(got, strings.TrimRight(in.Text(), "\r"))
+		}
+		if want := []string{"echo:" + cmd, "ok"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("reply to %v = %q, want %q", cmd, got, want)
+		}
+	}
+	if got, want := f.Lines(), []string{"G28", "M114"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("printer received %q, want %q", got, want)
+	}
+}
+
+func TestNewSerialBadBaud(t *testing.T) {
+	f, err := NewFakePrinter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := NewSerial(f.Port(), 1234); err == nil {
+		t.Error("NewSerial accepted a baud rate of 1234")
+	}
+}
@@ -0,0 +1,382 @@
+package pipbot
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"pipbot/graph/model"
+)
+
+// Recipe is a protocol file. Its transfers use the GraphQL model: a Node's
+// Grid is the name of a matrix on the deck and its Position a well in it.
+type Recipe struct {
+	File      string
+	Transfers []*model.Transfer
+	// at is where each transfer was declared, for errors.
+	at []filePos
+}
+
+// filePos is a line and column in a file, both 1-based.
+type filePos struct {
+	line, col int
+}
+
+// recipeRow is a transfer as it is written in a recipe file. CSV recipes have
+// a header row with these names as columns.
+type recipeRow struct {
+	SourceLabware string    `yaml:"source_labware" json:"source_labware"`
+	SourceWell    string    `yaml:"source_well" json:"source_well"`
+	DestLabware   string    `yaml:"dest_labware" json:"dest_labware"`
+	DestWell      string    `yaml:"dest_well" json:"dest_well"`
+	Volume        float64   `yaml:"volume" json:"volume"`
+	TipPolicy     TipPolicy `yaml:"tip_policy,omitempty" json:"tip_policy,omitempty"`
+	LiquidClass   string    `yaml:"liquid_class,omitempty" json:"liquid_class,omitempty"`
+	MixBefore     *Mix      `yaml:"mix_before,omitempty" json:"mix_before,omitempty"`
+	MixAfter      *Mix      `yaml:"mix_after,omitempty" json:"mix_after,omitempty"`
+	Name          string    `yaml:"name,omitempty" json:"name,omitempty"`
+	Group         string    `yaml:"group,omitempty" json:"group,omitempty"`
+	SampleID      string    `yaml:"sample_id,omitempty" json:"sample_id,omitempty"`
+}
+
+// recipeColumns are the CSV columns a recipe must have, and recipeOptional
+// those it may have.
+var (
+	recipeColumns  = []string{"source_labware", "source_well", "dest_labware", "dest_well", "volume"}
+	recipeOptional = []string{"tip_policy", "liquid_class", "mix_before", "mix_after", "name", "group", "sample_id"}
+)
+
+// transfer checks r and converts it to the GraphQL model.
+func (r *recipeRow) transfer(n int) (*model.Transfer, error) {
+	for _, f := range []struct{ name, val string }{
+		{"source_labware", r.SourceLabware}, {"source_well", r.SourceWell},
+		{"dest_labware", r.DestLabware}, {"dest_well", r.DestWell},
+	} {
+		if f.val == "" {
+			return nil, fmt.Errorf("%v is missing", f.name)
+		}
+	}
+	for _, w := range []string{r.SourceWell, r.DestWell} {
+		if _, _, err := ParseWell(w); err != nil {
+			return nil, err
+		}
+	}
+	if r.Volume <= 0 {
+		return nil, fmt.Errorf("volume %v must be positive", r.Volume)
+	}
+	if r.TipPolicy != "" && !r.TipPolicy.valid() {
+		return nil, fmt.Errorf("unknown tip policy %q", r.TipPolicy)
+	}
+	t := &model.Transfer{
+		ID:        strconv.Itoa(n),
+		SampleID:  r.SampleID,
+		Source:    &model.Node{Grid: r.SourceLabware, Position: strings.ToUpper(r.SourceWell), Aspirate: true},
+		Dest:      &model.Node{Grid: r.DestLabware, Position: strings.ToUpper(r.DestWell)},
+		Volume:    r.Volume,
+		MixBefore: r.MixBefore.model(),
+		MixAfter:  r.MixAfter.model(),
+	}
+	if r.TipPolicy != "" {
+		policy := string(r.TipPolicy)
+		t.TipPolicy = &policy
+	}
+	if r.LiquidClass != "" {
+		if _, err := LookupLiquidClass(r.LiquidClass); err != nil {
+			return nil, err
+		}
+		t.LiquidClass = &r.LiquidClass
+	}
+	if r.Name != "" {
+		t.Name = &r.Name
+	}
+	if r.Group != "" {
+		t.Group = &r.Group
+	}
+	return t, nil
+}
+
+// IsRecipe reports whether file is a recipe rather than a color plate map:
+// JSON or YAML, or a CSV whose first row is a recipe header.
+func IsRecipe(file string) bool {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	case ".csv":
+	default:
+		return false
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	head, err := r.Read()
+	if err != nil {
+		return false
+	}
+	for _, h := range head {
+		if strings.EqualFold(strings.TrimSpace(h), recipeColumns[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenRecipe reads file as a recipe, or as a plate map if it is not one or
+// legendFile is given.
+func OpenRecipe(file, legendFile string) (*Recipe, error) {
+	if legendFile == "" && IsRecipe(file) {
+		return LoadRecipe(file)
+	}
+	return LoadPlateMap(file, legendFile)
+}
+
+// LoadRecipe reads a recipe file. Files ending in .csv are read as CSV, .json
+// as JSON and anything else as YAML. Errors are *PlanError with the line they
+// were found on.
+//
+// CSV recipes have a header row naming the columns source_labware,
+// source_well, dest_labware, dest_well and volume, and optionally tip_policy,
+// liquid_class, mix_before, mix_after, name, group and sample_id, in any
+// order. JSON and
+// YAML recipes are a "transfers" list of objects with the same keys. Mixes are
+// written as ParseMix reads them, or in YAML and JSON as objects with cycles,
+// volume, height and rate.
+func LoadRecipe(file string) (*Recipe, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(file), ".csv") {
+		return readRecipeCSV(file, b)
+	}
+	return readRecipeYAML(file, b)
+}
+
+func readRecipeCSV(file string, b []byte) (*Recipe, error) {
+	res := &Recipe{File: file}
+	err := readTable(file, b, recipeColumns, recipeOptional, func(line int, get func(string) string) error {
+		row := &recipeRow{
+			SourceLabware: get("source_labware"),
+			SourceWell:    get("source_well"),
+			DestLabware:   get("dest_labware"),
+			DestWell:      get("dest_well"),
+			TipPolicy:     TipPolicy(strings.ToLower(get("tip_policy"))),
+			LiquidClass:   get("liquid_class"),
+			Name:          get("name"),
+			Group:         get("group"),
+			SampleID:      get("sample_id"),
+		}
+		var err error
+		if row.Volume, err = strconv.ParseFloat(get("volume"), 64); err != nil {
+			return &colError{col: "volume", err: fmt.Errorf("bad volume %q", get("volume"))}
+		}
+		for _, m := range []struct {
+			col string
+			mix **Mix
+		}{{"mix_before", &row.MixBefore}, {"mix_after", &row.MixAfter}} {
+			if v := get(m.col); v != "" {
+				if *m.mix, err = ParseMix(v); err != nil {
+					return &colError{col: m.col, err: err}
+				}
+			}
+		}
+		return res.add(row, line)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// readRecipeYAML reads YAML and JSON recipes. JSON goes through the YAML
+// parser too, which takes it as it is and keeps track of lines.
+func readRecipeYAML(file string, b []byte) (*Recipe, error) {
+	var doc struct {
+		Transfers []yaml.Node `yaml:"transfers"`
+	}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, &PlanError{File: file, Err: err}
+	}
+	res := &Recipe{File: file}
+	for _, n := range doc.Transfers {
+		row := &recipeRow{}
+		if err := n.Decode(row); err != nil {
+			return nil, &PlanError{File: file, Line: n.Line, Err: err}
+		}
+		if err := res.add(row, n.Line); err != nil {
+			return nil, err
+		}
+	}
+	if len(res.Transfers) == 0 {
+		return nil, &PlanError{File: file, Err: errors.New("recipe has no transfers")}
+	}
+	return res, nil
+}
+
+// add appends the transfer row declares on line.
+func (r *Recipe) add(row *recipeRow, line int) error {
+	t, err := row.transfer(len(r.Transfers) + 1)
+	if err != nil {
+		return &PlanError{File: r.File, Line: line, Err: err}
+	}
+	r.Transfers = append(r.Transfers, t)
+	r.at = append(r.at, filePos{line: line})
+	return nil
+}
+
+// errorAt reports err against where the i'th transfer was declared.
+func (r *Recipe) errorAt(i int, err error) error {
+	pe := &PlanError{File: r.File, Err: err}
+	if i < len(r.at) {
+		pe.Line, pe.Column = r.at[i].line, r.at[i].col
+	}
+	return pe
+}
+
+// node resolves n to a cell on the layout. A node without a Grid is in the
+// matrix playing role.
+func (l *Layout) node(n *model.Node, role Role) (*Cell, error) {
+	var m *Matrix
+	var err error
+	if n.Grid == "" {
+		m, err = l.Role(role)
+	} else {
+		m, err = l.Matrix(n.Grid)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m.Well(n.Position)
+}
+
+// WithGrids returns l with the grids of a stored recipe in place of its
+// matrices. Grids do not say what they hold, so one named like a matrix of l
+// takes its kind, role, pipette, depth and top from it. The tip racks of l
+// stay unless a grid replaces them.
+func (l *Layout) WithGrids(grids []*model.Grid) *Layout {
+	res := *l
+	res.Matrices = nil
+	named := make(map[string]bool, len(grids))
+	for _, g := range grids {
+		named[g.Name] = true
+	}
+	for _, m := range l.Matrices {
+		if m.Kind == Tip && !named[m.Name] {
+			res.Matrices = append(res.Matrices, m)
+		}
+	}
+	for _, g := range grids {
+		var home Position
+		if g.Home != nil {
+			home = Position{X: float32(g.Home.X), Y: float32(g.Home.Y), Z: float32(g.Home.Z)}
+		}
+		kind := Unknown
+		old, err := l.Matrix(g.Name)
+		if err == nil {
+			kind = old.Kind
+		}
+		m := NewMatrix(kind, g.Name, home, float32(g.RowSpace), float32(g.ColSpace), g.NRows, g.NCols)
+		if err == nil {
+			m.Role, m.Pipette, m.Top = old.Role, old.Pipette, old.Top
+			m.SetDepth(old.Depth)
+		}
+		res.Matrices = append(res.Matrices, m)
+	}
+	return &res
+}
+
+// PlanRecipe turns r into transfers on the bot's deck, assigning tips as it
+// goes. Transfers that can share a tip under the bot's TipPolicy, or their
+// own, are moved next to each other unless an earlier transfer has to come
+// between them. Sources that would run dry and destinations that would
+// overflow are caught here. Problems are reported against the line of the
+// transfer that has them. The bot must have been through Prepare or Init.
+func (b *PipBot) PlanRecipe(r *Recipe) ([]Action, error) {
+	if b.planTips == nil {
+		return nil, errors.New("the tip racks are not known until Prepare or Init")
+	}
+	def := b.TipPolicy
+	if def == "" {
+		def = DefaultTipPolicy
+	}
+	if !def.valid() {
+		return nil, fmt.Errorf("unknown tip policy %q", def)
+	}
+	plan := make([]planned, len(r.Transfers))
+	for i, t := range r.Transfers {
+		fail := func(err error) error {
+			return r.errorAt(i, err)
+		}
+		src, err := b.Layout.node(t.Source, Source)
+		if err != nil {
+			return nil, fail(err)
+		}
+		dest, err := b.Layout.node(t.Dest, Destination)
+		if err != nil {
+			return nil, fail(err)
+		}
+		if err = b.Pipette.Check(float32(t.Volume)); err != nil {
+			return nil, fail(err)
+		}
+		p := def
+		if t.TipPolicy != nil && *t.TipPolicy != "" {
+			p = TipPolicy(*t.TipPolicy)
+		}
+		plan[i] = planned{n: i, t: t, src: src, dest: dest, policy: p}
+		if !p.valid() {
+			return nil, fail(fmt.Errorf("unknown tip policy %q", p))
+		}
+		plan[i].class = src.Liquid
+		if t.LiquidClass != nil && *t.LiquidClass != "" {
+			if plan[i].class, err = LookupLiquidClass(*t.LiquidClass); err != nil {
+				return nil, fail(err)
+			}
+		}
+		if plan[i].mixBefore, err = mixFrom(t.MixBefore); err != nil {
+			return nil, fail(err)
+		}
+		if plan[i].mixAfter, err = mixFrom(t.MixAfter); err != nil {
+			return nil, fail(err)
+		}
+	}
+	plan = reorder(plan)
+
+	var steps []Action
+	hasTip := b.hasTip
+	cs := contents{}
+	for i, p := range plan {
+		if err := cs.move(p.src, p.dest, float32(p.t.Volume)); err != nil {
+			return nil, r.errorAt(p.n, err)
+		}
+		eject := i+1 == len(plan) || !p.shares(plan[i+1])
+		t := NewTransfer(b.Pipette, nil, p.src.Position, p.dest.Position, float32(p.t.Volume), eject)
+		t.src, t.dest = p.src, p.dest
+		t.MixBefore, t.MixAfter = p.mixBefore, p.mixAfter
+		t.Class = p.class
+		if err := t.pipette().Check(t.Volume); err != nil {
+			return nil, r.errorAt(p.n, err)
+		}
+		if !hasTip {
+			tip, ref, replace, err := b.getTip()
+			if err != nil {
+				return nil, r.errorAt(p.n, err)
+			}
+			if replace != nil {
+				steps = append(steps, replace)
+			}
+			t.Tip, t.tip = tip, ref
+		}
+		hasTip = !eject
+		steps = append(steps, t)
+	}
+	return steps, nil
+}
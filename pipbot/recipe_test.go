@@ -0,0 +1,139 @@
+package pipbot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"pipbot/graph/model"
+)
+
+// writeFile writes content to name in a fresh directory and returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// summary is what a test checks of a transfer.
+type summary struct {
+	Src, Dest string
+	Volume    float64
+	MixAfter  *model.Mix
+	TipPolicy string
+}
+
+func summarize(ts []*model.Transfer) []summary {
+	res := make([]summary, len(ts))
+	for i, t := range ts {
+		res[i] = summary{
+			Src:      t.Source.Grid + ":" + t.Source.Position,
+			Dest:     t.Dest.Grid + ":" + t.Dest.Position,
+			Volume:   t.Volume,
+			MixAfter: t.MixAfter,
+		}
+		if t.TipPolicy != nil {
+			res[i].TipPolicy = *t.TipPolicy
+		}
+	}
+	return res
+}
+
+func TestLoadRecipe(t *testing.T) {
+	want := []summary{
+		{Src: "12:A1", Dest: "96:B2", Volume: 50},
+		{Src: "12:B1", Dest: "96:C3", Volume: 12.5, MixAfter: &model.Mix{Cycles: 3, Volume: 20}, TipPolicy: "never"},
+	}
+	for _, tc := range []struct {
+		name, content string
+	}{
+		{name: "r.csv", content: `# a comment
+source_labware, source_well, dest_labware, dest_well, volume, mix_after, tip_policy
+12, A1, 96, B2, 50, ,
+12, B1, 96, c3, 12.5, 3x20, Never
+`},
+		{name: "r.yaml", content: `transfers:
+  - {source_labware: "12", source_well: A1, dest_labware: "96", dest_well: B2, volume: 50}
+  - source_labware: "12"
+    source_well: B1
+    dest_labware: "96"
+    dest_well: c3
+    volume: 12.5
+    mix_after: {cycles: 3, volume: 20}
+    tip_policy: never
+`},
+		{name: "r.json", content: `{"transfers": [
+  {"source_labware": "12", "source_well": "A1", "dest_labware": "96", "dest_well": "B2", "volume": 50},
+  {"source_labware": "12", "source_well": "B1", "dest_labware": "96", "dest_well": "c3", "volume": 12.5,
+   "mix_after": {"cycles": 3, "volume": 20}, "tip_policy": "never"}
+]}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := LoadRecipe(writeFile(t, tc.name, tc.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := summarize(r.Transfers); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadRecipeErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name, content string
+		// want is the error without the directory of the file
+		want string
+	}{
+		{name: "volume.csv", content: "source_labware,source_well,dest_labware,dest_well,volume\n12,A1,96,A1,10\n12,A2,96,A2,lots\n",
+			want: `volume.csv:3:5: bad volume "lots"`},
+		{name: "well.csv", content: "source_labware,source_well,dest_labware,dest_well,volume\n12,A1,96,99,10\n",
+			want: `well.csv:2: bad well name "99"`},
+		{name: "mix.csv", content: "source_labware,source_well,dest_labware,dest_well,volume,mix_before\n12,A1,96,A1,10,often\n",
+			want: "mix.csv:2:6: "},
+		{name: "column.csv", content: "source_labware,source_well,dest_labware,dest_well,volume,colour\n",
+			want: `column.csv:1: unknown column "colour"`},
+		{name: "missing.csv", content: "source_labware,source_well,dest_labware,volume\n",
+			want: `missing.csv:1: missing column "dest_well"`},
+		{name: "volume.yaml", content: "transfers:\n  - {source_labware: a, source_well: A1, dest_labware: b, dest_well: A1, volume: 5}\n  - {source_labware: a, source_well: A1, dest_labware: b, dest_well: A1, volume: -5}\n",
+			want: "volume.yaml:3: volume -5 must be positive"},
+		{name: "policy.json", content: "{\"transfers\": [\n  {\"source_labware\": \"a\", \"source_well\": \"A1\", \"dest_labware\": \"b\", \"dest_well\": \"A1\", \"volume\": 5, \"tip_policy\": \"sometimes\"}\n]}",
+			want: `policy.json:2: unknown tip policy "sometimes"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			file := writeFile(t, tc.name, tc.content)
+			_, err := LoadRecipe(file)
+			var pe *PlanError
+			if !errors.As(err, &pe) {
+				t.Fatalf("got %v, want a *PlanError", err)
+			}
+			pe.File = filepath.Base(pe.File)
+			if got := pe.Error(); len(got) < len(tc.want) || got[:len(tc.want)] != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPlanRecipeErrorLine(t *testing.T) {
+	b := NewPipBotOn(NewSimulator(nil))
+	file := writeFile(t, "r.csv", "source_labware,source_well,dest_labware,dest_well,volume\n12,A1,96,A1,10\n12,A1,nowhere,A1,10\n")
+	r, err := LoadRecipe(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = b.PlanRecipe(r); err == nil {
+		t.Fatal("planned before Prepare")
+	}
+	actions, err := b.Plan(file)
+	var pe *PlanError
+	if !errors.As(err, &pe) || pe.Line != 3 {
+		t.Fatalf("got %v and %v actions, want an error on line 3", err, len(actions))
+	}
+}
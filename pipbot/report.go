@@ -0,0 +1,278 @@
+package pipbot
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+)
+
+// StockShare is how much of one stock is in a well.
+type StockShare struct {
+	// Stock is the well the stock was filled into, as "matrix:well".
+	Stock  string  `json:"stock"`
+	Volume float32 `json:"volume"`
+	// Fraction is the share of the well's volume that is the stock, 0 to 1.
+	Fraction float32 `json:"fraction"`
+	// Concentration is what the stock comes to in the well, when the deck
+	// gives the stock's own.
+	Concentration *Quantity `json:"concentration,omitempty"`
+}
+
+// WellReport is what ended up in one destination well.
+type WellReport struct {
+	Matrix string       `json:"matrix"`
+	Well   string       `json:"well"`
+	Volume float32      `json:"volume"`
+	Stocks []StockShare `json:"stocks"`
+}
+
+// Report is the composition of every well a protocol fills, resolved down to
+// the stocks it was made from.
+type Report struct {
+	// Stocks are the stocks found in any well, in deck order, and
+	// Concentrations those of the stocks whose concentration is known.
+	Stocks         []string            `json:"stocks"`
+	Concentrations map[string]Quantity `json:"concentrations,omitempty"`
+	Wells          []WellReport        `json:"wells"`
+}
+
+// Report works out what r leaves in each of its destination wells, starting
+// from what the deck holds now.
+func (l *Layout) Report(r *Recipe) (*Report, error) {
+	cs := contents{}
+	dests := make(map[*Cell]bool)
+	for i, t := range r.Transfers {
+		src, err := l.node(t.Source, Source)
+		if err != nil {
+			return nil, r.errorAt(i, err)
+		}
+		dest, err := l.node(t.Dest, Destination)
+		if err != nil {
+			return nil, r.errorAt(i, err)
+		}
+		if err = cs.move(src, dest, float32(t.Volume)); err != nil {
+			return nil, r.errorAt(i, err)
+		}
+		dests[dest] = true
+	}
+	return l.report(cs, dests), nil
+}
+
+// RunReport reports what the destination wells of actions hold now, after
+// they have been run.
+func (l *Layout) RunReport(actions []Action) *Report {
+	dests := make(map[*Cell]bool)
+	for _, a := range actions {
+		if t, ok := a.(*Transfer); ok && t.dest != nil {
+			dests[t.dest] = true
+		}
+	}
+	return l.report(nil, dests)
+}
+
+// report lists the contents of dests in deck order.
+func (l *Layout) report(cs contents, dests map[*Cell]bool) *Report {
+	names := make(map[*Cell]string)
+	order := make(map[*Cell]int)
+	for _, m := range l.Matrices {
+		for row, cells := range m.Cells {
+			for col, c := range cells {
+				names[c] = m.Name + ":" + WellName(row, col)
+				order[c] = len(order)
+			}
+		}
+	}
+	res := &Report{}
+	seen := make(map[*Cell]bool)
+	for _, m := range l.Matrices {
+		for row, cells := range m.Cells {
+			for col, c := range cells {
+				if !dests[c] {
+					continue
+				}
+				mix := cs.of(c)
+				w := WellReport{Matrix: m.Name, Well: WellName(row, col), Volume: mix.Volume()}
+				stocks := make([]*Cell, 0, len(mix.Contents))
+				for s, v := range mix.Contents {
+					if v > volumeSlack {
+						stocks = append(stocks, s)
+					}
+				}
+				sort.Slice(stocks, func(i, j int) bool { return order[stocks[i]] < order[stocks[j]] })
+				for _, s := range stocks {
+					share := StockShare{Stock: names[s], Volume: mix.Contents[s], Fraction: mix.Fraction(s)}
+					if s.Concentration != nil {
+						c := s.Concentration.diluted(share.Fraction)
+						share.Concentration = &c
+					}
+					w.Stocks = append(w.Stocks, share)
+					seen[s] = true
+				}
+				res.Wells = append(res.Wells, w)
+			}
+		}
+	}
+	all := make([]*Cell, 0, len(seen))
+	for s := range seen {
+		all = append(all, s)
+	}
+	sort.Slice(all, func(i, j int) bool { return order[all[i]] < order[all[j]] })
+	for _, s := range all {
+		res.Stocks = append(res.Stocks, names[s])
+		if s.Concentration != nil {
+			if res.Concentrations == nil {
+				res.Concentrations = make(map[string]Quantity)
+			}
+			res.Concentrations[names[s]] = *s.Concentration
+		}
+	}
+	return res
+}
+
+// share returns what w holds of stock, or nil.
+func (w *WellReport) share(stock string) *StockShare {
+	for i := range w.Stocks {
+		if w.Stocks[i].Stock == stock {
+			return &w.Stocks[i]
+		}
+	}
+	return nil
+}
+
+// amount is how much of stock w holds: its concentration there in the unit
+// of the stock's, or its percentage by volume when that is not known.
+func (r *Report) amount(w *WellReport, stock string) float32 {
+	s := w.share(stock)
+	if s == nil {
+		return 0
+	}
+	if _, ok := r.Concentrations[stock]; ok {
+		return s.Concentration.Value
+	}
+	return 100 * s.Fraction
+}
+
+// unit is what amount is measured in for stock.
+func (r *Report) unit(stock string) string {
+	if c, ok := r.Concentrations[stock]; ok && c.Unit != "" {
+		return c.Unit
+	}
+	if _, ok := r.Concentrations[stock]; ok {
+		return "concentration"
+	}
+	return "% v/v"
+}
+
+// num formats v to two decimal places, without trailing zeros.
+func num(v float32) string {
+	return strconv.FormatFloat(math.Round(float64(v)*100)/100, 'f', -1, 64)
+}
+
+// WriteCSV writes one row per well: its matrix, well and volume in µL, then
+// the concentration of each stock, or its percentage by volume when the deck
+// does not give the stock's concentration. The header names the unit.
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	head := []string{"matrix", "well", "volume"}
+	for _, s := range r.Stocks {
+		head = append(head, fmt.Sprintf("%v (%v)", s, r.unit(s)))
+	}
+	if err := cw.Write(head); err != nil {
+		return err
+	}
+	for i := range r.Wells {
+		well := &r.Wells[i]
+		rec := []string{well.Matrix, well.Well, num(well.Volume)}
+		for _, s := range r.Stocks {
+			rec = append(rec, num(r.amount(well, s)))
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes r as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(r)
+}
+
+// plateTable is one plate map of a report: a title and what to show in each
+// well.
+type plateTable struct {
+	title string
+	cell  func(*WellReport) string
+}
+
+// WriteTable draws each destination matrix as a plate map: one of its
+// volumes in µL, then one of the amount of each stock in it, as in WriteCSV.
+func (r *Report) WriteTable(w io.Writer) error {
+	var plates []string
+	wells := make(map[string]map[string]*WellReport)
+	for i := range r.Wells {
+		well := &r.Wells[i]
+		if wells[well.Matrix] == nil {
+			plates = append(plates, well.Matrix)
+			wells[well.Matrix] = make(map[string]*WellReport)
+		}
+		wells[well.Matrix][well.Well] = well
+	}
+	for _, p := range plates {
+		rows, cols := 0, 0
+		for name := range wells[p] {
+			row, col, _ := ParseWell(name)
+			rows, cols = maxInt(rows, row+1), maxInt(cols, col+1)
+		}
+		tables := []plateTable{{fmt.Sprintf("%v: volume (µL)", p), func(w *WellReport) string { return num(w.Volume) }}}
+		for _, s := range r.Stocks {
+			s := s
+			used := false
+			for _, well := range wells[p] {
+				used = used || well.share(s) != nil
+			}
+			if used {
+				tables = append(tables, plateTable{fmt.Sprintf("%v: %v (%v)", p, s, r.unit(s)), func(w *WellReport) string { return num(r.amount(w, s)) }})
+			}
+		}
+		for _, t := range tables {
+			fmt.Fprintf(w, "%v\n", t.title)
+			tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', tabwriter.AlignRight)
+			for col := 0; col < cols; col++ {
+				fmt.Fprintf(tw, "\t%v", col+1)
+			}
+			fmt.Fprintln(tw, "\t")
+			for row := 0; row < rows; row++ {
+				fmt.Fprint(tw, rowName(row))
+				for col := 0; col < cols; col++ {
+					cell := ""
+					if well, ok := wells[p][WellName(row, col)]; ok {
+						cell = t.cell(well)
+					}
+					fmt.Fprintf(tw, "\t%v", cell)
+				}
+				fmt.Fprintln(tw, "\t")
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	return nil
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,109 @@
+package pipbot
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const reportDeck = `matrices:
+  - name: tips
+    kind: tip
+    home: {x: 165, y: 103.5, z: 73.5}
+    row_space: 8.8
+    col_space: 8.8
+    rows: 12
+    cols: 8
+  - name: stocks
+    role: source
+    home: {x: 46, y: 178.5, z: 75}
+    row_space: 26
+    col_space: 26
+    rows: 1
+    cols: 2
+    contents: {A1: 1000, A2: 1000}
+    concentrations: {A1: 10 mM}
+  - name: plate
+    role: destination
+    home: {x: 35.5, y: 86.5, z: 74.5}
+    row_space: 9
+    col_space: 9
+    rows: 1
+    cols: 2
+`
+
+const reportRecipe = `source_labware,source_well,dest_labware,dest_well,volume
+stocks,A1,plate,A1,100
+stocks,A2,plate,A1,100
+plate,A1,plate,A2,50
+stocks,A2,plate,A2,50
+`
+
+func TestReportConcentrations(t *testing.T) {
+	dir := t.TempDir()
+	deck, recipe := filepath.Join(dir, "deck.yaml"), filepath.Join(dir, "recipe.csv")
+	if err := os.WriteFile(deck, []byte(reportDeck), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(recipe, []byte(reportRecipe), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := LoadDeck(deck)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := d.Layout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := LoadRecipe(recipe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep, err := l.Report(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err = rep.WriteCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	// plate:A2 is a 1 in 2 dilution of plate:A1, itself 1 in 2 of the stock
+	want := `matrix,well,volume,stocks:A1 (mM),stocks:A2 (% v/v)
+plate,A1,150,5,50
+plate,A2,100,2.5,75
+`
+	if got := b.String(); got != want {
+		t.Errorf("report is\n%v\nwant\n%v", got, want)
+	}
+	b.Reset()
+	if err = rep.WriteJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `"concentration": "2.5 mM"`) {
+		t.Errorf("JSON report has no concentration for plate:A2:\n%v", b.String())
+	}
+}
+
+func TestQuantityText(t *testing.T) {
+	for in, want := range map[string]Quantity{
+		"10 mM":    {Value: 10, Unit: "mM"},
+		"5mg/mL":   {Value: 5, Unit: "mg/mL"},
+		"1e-3 M":   {Value: 0.001, Unit: "M"},
+		"2.5 µg/L": {Value: 2.5, Unit: "µg/L"},
+		"40":       {Value: 40},
+	} {
+		var q Quantity
+		if err := q.UnmarshalText([]byte(in)); err != nil || q != want {
+			t.Errorf("%q parsed as %+v, %v; want %+v", in, q, err, want)
+		}
+	}
+	for _, in := range []string{"", "mM", "-1 mM"} {
+		var q Quantity
+		if err := q.UnmarshalText([]byte(in)); err == nil {
+			t.Errorf("%q parsed as %+v", in, q)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+//go:build linux
+
+package pipbot
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+var baudRates = map[int]uint32{
+	9600:    unix.B9600,
+	19200:   unix.B19200,
+	38400:   unix.B38400,
+	57600:   unix.B57600,
+	115200:  unix.B115200,
+	230400:  unix.B230400,
+	460800:  unix.B460800,
+	500000:  unix.B500000,
+	921600:  unix.B921600,
+	1000000: unix.B1000000,
+}
+
+// NewSerial opens the tty at port and puts it in raw 8N1 mode at baud, which
+// is what Marlin expects on its USB serial port.
+func NewSerial(port string, baud int) (Transport, error) {
+	speed, ok := baudRates[baud]
+	if !ok {
+		return nil, fmt.Errorf("unsupported baud rate %v", baud)
+	}
+	f, err := os.OpenFile(port, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err = makeRaw(f, speed); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("configure %v: %w", port, err)
+	}
+	return f, nil
+}
+
+// control runs fn on the descriptor behind f. Unlike f.Fd() it leaves the file
+// in non-blocking mode, so Close still interrupts a pending Read.
+func control(f *os.File, fn func(fd int) error) error {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var ferr error
+	if err = rc.Control(func(fd uintptr) { ferr = fn(int(fd)) }); err != nil {
+		return err
+	}
+	return ferr
+}
+
+// makeRaw is cfmakeraw plus cfsetspeed on the terminal f.
+func makeRaw(f *os.File, speed uint32) error {
+	return control(f, func(fd int) error {
+		return setRaw(fd, speed)
+	})
+}
+
+func setRaw(fd int, speed uint32) error {
+	t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return err
+	}
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB | unix.CSTOPB | unix.CRTSCTS | unix.CBAUD
+	t.Cflag |= unix.CS8 | unix.CREAD | unix.CLOCAL | speed
+	t.Cc[unix.VMIN] = 1
+	t.Cc[unix.VTIME] = 0
+	return unix.IoctlSetTermios(fd, unix.TCSETS, t)
+}
+
+// Cbreak stops the terminal f from echoing and from waiting for a newline, so
+// single key presses can be read as they come. Ctrl-C arrives as a key too
+// rather than killing the process with the terminal still changed. The
+// returned func puts the terminal back.
+func Cbreak(f *os.File) (restore func() error, err error) {
+	var old *unix.Termios
+	err = control(f, func(fd int) error {
+		t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+		if err != nil {
+			return err
+		}
+		saved := *t
+		old = &saved
+		t.Lflag &^= unix.ECHO | unix.ICANON | unix.ISIG
+		t.Cc[unix.VMIN] = 1
+		t.Cc[unix.VTIME] = 0
+		return unix.IoctlSetTermios(fd, unix.TCSETS, t)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		return control(f, func(fd int) error {
+			return unix.IoctlSetTermios(fd, unix.TCSETS, old)
+		})
+	}, nil
+}
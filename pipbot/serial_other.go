@@ -0,0 +1,21 @@
+//go:build !linux
+
+package pipbot
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// NewSerial is only implemented on linux. Use a ".gcode" port to write the
+// command stream to a file instead.
+func NewSerial(port string, baud int) (Transport, error) {
+	return nil, fmt.Errorf("open %v: serial ports are not supported on %v", port, runtime.GOOS)
+}
+
+// Cbreak is only implemented on linux. Elsewhere keys are read a line at a
+// time.
+func Cbreak(f *os.File) (restore func() error, err error) {
+	return nil, fmt.Errorf("terminal modes are not supported on %v", runtime.GOOS)
+}
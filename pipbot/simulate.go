@@ -0,0 +1,352 @@
+package pipbot
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	// HomeFeed is the feed rate the simulator assumes for homing, in mm/min.
+	HomeFeed float64 = 3000
+	// roomTemperature is the temperature the virtual hotend sits at, in °C.
+	roomTemperature = 20
+)
+
+// messages are the commands whose arguments are a message to show.
+var messages = map[string]bool{"M0": true, "M1": true, "M117": true}
+
+// Simulator is a Transport with a virtual printer on the other end instead
+// of a real one. It runs the G-code the bot sends (G0, G1, G4, G28, G90, G91,
+// G92, M0, M1, M82, M83, M114, M117, M201, M204, M302 and M400) against X, Y,
+// Z and E state and keeps a timeline of what happened. Like a .gcode file it
+// never replies.
+type Simulator struct {
+	// Pipette converts plunger travel to µL in the results, if set.
+	Pipette *Pipette
+	sim     Simulation
+	partial []byte
+	line    int
+	// the modal state of the virtual printer
+	at                 Position
+	e                  float32
+	feed               float64
+	accel              float64
+	homed, coldExtrude bool
+	relative, relE     bool
+}
+
+// Simulation is what a Simulator saw. Travel and plunger movements are in mm,
+// and Drawn and Dispensed also in µL when the simulator has a pipette.
+type Simulation struct {
+	Timeline []SimStep
+	Duration time.Duration
+	Travel   float32
+	Plunger  PlungerTotals
+	Warnings []SimWarning
+	// ul is set when the µL totals are known.
+	ul bool
+}
+
+// PlungerTotals is how far the plunger was raised to draw and lowered to
+// dispense.
+type PlungerTotals struct {
+	DrawnMM, DispensedMM float32
+	DrawnUL, DispensedUL float32
+}
+
+// SimStep is one line of G-code as the simulator ran it. At and E are where
+// the machine was once it was done.
+type SimStep struct {
+	Line     int
+	Command  string
+	Start    time.Duration
+	Duration time.Duration
+	At       Position
+	E        float32
+}
+
+// SimWarning is something the printer would have done wrong, or refused.
+type SimWarning struct {
+	Line int
+	Msg  string
+}
+
+func (w SimWarning) String() string {
+	return fmt.Sprintf("line %v: %v", w.Line, w.Msg)
+}
+
+// NewSimulator returns a simulator for a printer that has just been switched
+// on, with the plunger measured by p if it is not nil.
+func NewSimulator(p *Pipette) *Simulator {
+	return &Simulator{Pipette: p, feed: DefaultSpeeds.XY}
+}
+
+// Simulate runs the G-code in r, such as a file written by the bot, on a
+// fresh simulator.
+func Simulate(r io.Reader, p *Pipette) (*Simulation, error) {
+	s := NewSimulator(p)
+	if _, err := io.Copy(s, r); err != nil {
+		return nil, err
+	}
+	return s.Result(), nil
+}
+
+// Write runs every complete line in b.
+func (s *Simulator) Write(b []byte) (int, error) {
+	s.partial = append(s.partial, b...)
+	for {
+		i := bytes.IndexByte(s.partial, '\n')
+		if i < 0 {
+			break
+		}
+		s.exec(string(s.partial[:i]))
+		s.partial = s.partial[i+1:]
+	}
+	return len(b), nil
+}
+
+// Read reports io.EOF: the virtual printer never answers.
+func (s *Simulator) Read([]byte) (int, error) {
+	return 0, io.EOF
+}
+
+// Close runs any last line without a newline.
+func (s *Simulator) Close() error {
+	if len(bytes.TrimSpace(s.partial)) > 0 {
+		s.exec(string(s.partial))
+	}
+	s.partial = nil
+	return nil
+}
+
+// Result returns what the simulator has seen so far.
+func (s *Simulator) Result() *Simulation {
+	res := s.sim
+	res.Timeline = append([]SimStep(nil), s.sim.Timeline...)
+	res.Warnings = append([]SimWarning(nil), s.sim.Warnings...)
+	res.ul = s.Pipette != nil
+	if s.Pipette != nil {
+		res.Plunger.DrawnUL = s.Pipette.Curve.airVolume(res.Plunger.DrawnMM)
+		res.Plunger.DispensedUL = s.Pipette.Curve.airVolume(res.Plunger.DispensedMM)
+	}
+	return &res
+}
+
+func (s *Simulator) warn(format string, a ...any) {
+	s.sim.Warnings = append(s.sim.Warnings, SimWarning{Line: s.line, Msg: fmt.Sprintf(format, a...)})
+}
+
+// exec runs one line of G-code.
+func (s *Simulator) exec(line string) {
+	s.line++
+	if i := strings.IndexByte(line, ';'); i >= 0 {
+		line = line[:i]
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	fields := strings.Fields(line)
+	cmd := strings.ToUpper(fields[0])
+	args := map[byte]float64{}
+	// the rest of a message is free text, not arguments
+	if !messages[cmd] {
+		for _, f := range fields[1:] {
+			v, err := strconv.ParseFloat(f[1:], 64)
+			if err != nil && len(f) > 1 {
+				s.warn("bad argument %q", f)
+				continue
+			}
+			args[strings.ToUpper(f)[0]] = v
+		}
+	}
+	var took time.Duration
+	switch cmd {
+	case "G0", "G1":
+		took = s.move(args)
+	case "G4":
+		took = time.Duration(args['P']*float64(time.Millisecond)) + time.Duration(args['S']*float64(time.Second))
+	case "G28":
+		took = s.travel(Position{}, 0, HomeFeed)
+		s.at, s.homed = Position{}, true
+	case "G90", "G91":
+		// as in Marlin these switch E as well, until M82 or M83
+		s.relative = cmd == "G91"
+		s.relE = s.relative
+	case "G92":
+		if v, ok := args['E']; ok {
+			s.e = float32(v)
+		}
+		for axis, p := range map[byte]*float32{'X': &s.at.X, 'Y': &s.at.Y, 'Z': &s.at.Z} {
+			if v, ok := args[axis]; ok {
+				*p = float32(v)
+			}
+		}
+	case "M0", "M1":
+		s.warn("the printer waits here for its button to be pressed")
+	case "M82", "M83":
+		s.relE = cmd == "M83"
+	case "M204":
+		if v, ok := args['S']; ok {
+			s.accel = v
+		}
+	case "M302":
+		// P1 turns the protection off, and S sets the lowest temperature E
+		// may move at; anything under room temperature lets it move cold
+		if p, ok := args['P']; ok {
+			s.coldExtrude = p > 0
+		} else if t, ok := args['S']; ok {
+			s.coldExtrude = t < roomTemperature
+		}
+	case "M114", "M117", "M201", "M400":
+	default:
+		s.warn("unknown command %v", fields[0])
+	}
+	s.sim.Timeline = append(s.sim.Timeline, SimStep{
+		Line: s.line, Command: line, Start: s.sim.Duration, Duration: took, At: s.at, E: s.e,
+	})
+	s.sim.Duration += took
+}
+
+// move runs a G0 or G1.
+func (s *Simulator) move(args map[byte]float64) time.Duration {
+	if f, ok := args['F']; ok {
+		if f <= 0 {
+			s.warn("feed rate F%v is not positive", f)
+		} else {
+			s.feed = f
+		}
+	}
+	to, e := s.at, s.e
+	for axis, p := range map[byte]*float32{'X': &to.X, 'Y': &to.Y, 'Z': &to.Z} {
+		if v, ok := args[axis]; ok {
+			if s.relative {
+				*p += float32(v)
+			} else {
+				*p = float32(v)
+			}
+		}
+	}
+	if v, ok := args['E']; ok {
+		if s.relE {
+			e += float32(v)
+		} else {
+			e = float32(v)
+		}
+	}
+	if to != s.at && !s.homed {
+		s.warn("moves before the printer is homed")
+	}
+	if to != s.at {
+		if err := to.check(); err != nil {
+			s.warn("%v", err)
+		}
+	}
+	de := e - s.e
+	if de != 0 {
+		if !s.coldExtrude {
+			s.warn("the firmware refuses to move E while cold; send M302 S1 first")
+			de, e = 0, s.e
+		}
+		// E0 is the plunger all the way down and drawing makes E negative
+		if e > 0 {
+			s.warn("the plunger is pushed past the bottom, to E%v", e)
+		}
+	}
+	if de < 0 {
+		s.sim.Plunger.DrawnMM -= de
+	} else {
+		s.sim.Plunger.DispensedMM += de
+	}
+	took := s.travel(to, de, s.feed)
+	s.e = e
+	return took
+}
+
+// travel moves to to, with de of plunger travel alongside, at feed and
+// returns how long it takes. As in Marlin the feed rate is along X, Y and Z
+// when they move and along E otherwise.
+func (s *Simulator) travel(to Position, de float32, feed float64) time.Duration {
+	dx, dy, dz := float64(to.X-s.at.X), float64(to.Y-s.at.Y), float64(to.Z-s.at.Z)
+	d := math.Sqrt(dx*dx + dy*dy + dz*dz)
+	s.sim.Travel += float32(d)
+	s.at = to
+	if d == 0 {
+		d = math.Abs(float64(de))
+	}
+	if d == 0 || feed <= 0 {
+		return 0
+	}
+	v := feed / 60
+	secs := d / v
+	if a := s.accel; a > 0 {
+		// speeding up and slowing down, at a mm/s² each way
+		if d >= v*v/a {
+			secs = d/v + v/a
+		} else {
+			secs = 2 * math.Sqrt(d/a)
+		}
+	}
+	return time.Duration(secs * float64(time.Second))
+}
+
+// airVolume is the volume of air travel mm of plunger moves, the inverse of
+// air.
+func (c Curve) airVolume(travel float32) float32 {
+	if travel <= 0 {
+		return 0
+	}
+	// air only grows with volume, so it can be inverted by bisection
+	lo, hi := float32(0), float32(1)
+	for c.air(hi) < travel && hi < 1e9 {
+		hi *= 2
+	}
+	for i := 0; i < 50; i++ {
+		mid := (lo + hi) / 2
+		if c.air(mid) < travel {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hundredths((lo + hi) / 2)
+}
+
+// WriteSummary writes the totals and warnings of s.
+func (s *Simulation) WriteSummary(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "duration:  %v\n", s.Duration.Round(time.Second))
+	fmt.Fprintf(bw, "travel:    %v mm\n", hundredths(s.Travel))
+	p := s.Plunger
+	if s.ul {
+		fmt.Fprintf(bw, "drawn:     %v mm of plunger, %v µL\n", hundredths(p.DrawnMM), p.DrawnUL)
+		fmt.Fprintf(bw, "dispensed: %v mm of plunger, %v µL\n", hundredths(p.DispensedMM), p.DispensedUL)
+	} else {
+		fmt.Fprintf(bw, "drawn:     %v mm of plunger\n", hundredths(p.DrawnMM))
+		fmt.Fprintf(bw, "dispensed: %v mm of plunger\n", hundredths(p.DispensedMM))
+	}
+	fmt.Fprintf(bw, "warnings:  %v\n", len(s.Warnings))
+	for _, warn := range s.Warnings {
+		fmt.Fprintf(bw, "  %v\n", warn)
+	}
+	return bw.Flush()
+}
+
+// WriteTimeline writes every step of s with when it started and where the
+// machine was after it.
+func (s *Simulation) WriteTimeline(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "time\tline\tcommand\tX\tY\tZ\tE")
+	for _, st := range s.Timeline {
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n", st.Start.Round(time.Millisecond), st.Line, st.Command,
+			hundredths(st.At.X), hundredths(st.At.Y), hundredths(st.At.Z), hundredths(st.E))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,50 @@
+package pipbot
+
+import (
+	"strings"
+	"testing"
+
+	"pipbot/graph/model"
+)
+
+// TestSimulateRecipe runs a recipe that uses up the tip rack on a Simulator.
+// The only thing it should warn about is waiting for the rack to be
+// replaced.
+func TestSimulateRecipe(t *testing.T) {
+	sim := NewSimulator(StockPipette())
+	b := NewPipBotOn(sim)
+	b.TipPolicy = TipAlways
+	if err := b.Init(); err != nil {
+		t.Fatal(err)
+	}
+	var volume float32
+	r := &Recipe{}
+	for i := 0; i < 100; i++ {
+		r.Transfers = append(r.Transfers, &model.Transfer{
+			Source: &model.Node{Grid: "12", Position: WellName(i%3, i%4)},
+			Dest:   &model.Node{Grid: "96", Position: WellName(i%8, i/8%12)},
+			Volume: 10,
+		})
+		volume += 10
+	}
+	actions, err := b.PlanRecipe(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = b.Run(actions); err != nil {
+		t.Fatal(err)
+	}
+	if err = sim.Close(); err != nil {
+		t.Fatal(err)
+	}
+	res := sim.Result()
+	if len(res.Warnings) != 1 || !strings.Contains(res.Warnings[0].Msg, "button") {
+		t.Errorf("warnings %v, want just the one for the rack swap", res.Warnings)
+	}
+	if res.Duration <= 0 || res.Travel <= 0 {
+		t.Errorf("took %v over %v mm", res.Duration, res.Travel)
+	}
+	if res.Plunger.DrawnUL < volume || res.Plunger.DispensedUL < volume {
+		t.Errorf("drew %v µL and dispensed %v µL, want at least %v µL", res.Plunger.DrawnUL, res.Plunger.DispensedUL, volume)
+	}
+}
@@ -0,0 +1,99 @@
+package pipbot
+
+import "fmt"
+
+// Speeds is how fast the gantry moves. Feed rates are in mm/min and
+// accelerations in mm/s². Fields left at 0 are taken from the layer below:
+// DefaultSpeeds, then the deck, then the liquid class of a transfer.
+type Speeds struct {
+	// XY is the feed rate across the bed and Z that of moves up and down.
+	XY float64 `yaml:"xy,omitempty" json:"xy,omitempty"`
+	Z  float64 `yaml:"z,omitempty" json:"z,omitempty"`
+	// Approach is the feed rate for going down into a well, from its rim to
+	// where the tip stops. It is Z if 0.
+	Approach float64 `yaml:"approach,omitempty" json:"approach,omitempty"`
+	// Plunger is the fastest the plunger may be driven. Flow rates that
+	// would need more are held to it.
+	Plunger float64 `yaml:"plunger,omitempty" json:"plunger,omitempty"`
+	// Acceleration is set with M204 and AxisAcceleration caps each axis with
+	// M201 when a run starts. They are only read from the deck.
+	Acceleration     float64 `yaml:"acceleration,omitempty" json:"acceleration,omitempty"`
+	AxisAcceleration Axes    `yaml:"axis_acceleration,omitempty" json:"axis_acceleration,omitempty"`
+}
+
+// Axes is a value for each axis of the printer, E being the plunger.
+type Axes struct {
+	X float64 `yaml:"x,omitempty" json:"x,omitempty"`
+	Y float64 `yaml:"y,omitempty" json:"y,omitempty"`
+	Z float64 `yaml:"z,omitempty" json:"z,omitempty"`
+	E float64 `yaml:"e,omitempty" json:"e,omitempty"`
+}
+
+// DefaultSpeeds are the speeds the bot was tuned with.
+var DefaultSpeeds = Speeds{XY: 1000, Z: 1000}
+
+// with returns s with the fields set in o replacing its own.
+func (s Speeds) with(o Speeds) Speeds {
+	for _, f := range []struct{ dst, src *float64 }{
+		{&s.XY, &o.XY}, {&s.Z, &o.Z}, {&s.Approach, &o.Approach}, {&s.Plunger, &o.Plunger},
+		{&s.Acceleration, &o.Acceleration},
+		{&s.AxisAcceleration.X, &o.AxisAcceleration.X}, {&s.AxisAcceleration.Y, &o.AxisAcceleration.Y},
+		{&s.AxisAcceleration.Z, &o.AxisAcceleration.Z}, {&s.AxisAcceleration.E, &o.AxisAcceleration.E},
+	} {
+		if *f.src > 0 {
+			*f.dst = *f.src
+		}
+	}
+	return s
+}
+
+// approach is the feed rate into wells.
+func (s Speeds) approach() float64 {
+	if s.Approach > 0 {
+		return s.Approach
+	}
+	return s.Z
+}
+
+// Validate checks that none of the speeds are negative.
+func (s Speeds) Validate() error {
+	a := s.AxisAcceleration
+	for _, v := range []float64{s.XY, s.Z, s.Approach, s.Plunger, s.Acceleration, a.X, a.Y, a.Z, a.E} {
+		if v < 0 {
+			return fmt.Errorf("speeds cannot be negative")
+		}
+	}
+	return nil
+}
+
+// setup is the G-code setting the accelerations, if any are given.
+func (s Speeds) setup() []string {
+	var res []string
+	m201 := "M201"
+	for _, a := range []struct {
+		axis string
+		v    float64
+	}{{"X", s.AxisAcceleration.X}, {"Y", s.AxisAcceleration.Y}, {"Z", s.AxisAcceleration.Z}, {"E", s.AxisAcceleration.E}} {
+		if a.v > 0 {
+			m201 += fmt.Sprintf(" %v%v", a.axis, a.v)
+		}
+	}
+	if m201 != "M201" {
+		res = append(res, m201)
+	}
+	if s.Acceleration > 0 {
+		res = append(res, fmt.Sprintf("M204 S%v", s.Acceleration))
+	}
+	return res
+}
+
+// pipette returns p with its plunger held to s.Plunger, or p itself if there
+// is no limit.
+func (s Speeds) pipette(p *Pipette) *Pipette {
+	if s.Plunger <= 0 {
+		return p
+	}
+	res := *p
+	res.maxFeed = float32(s.Plunger)
+	return &res
+}
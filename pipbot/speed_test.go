@@ -0,0 +1,110 @@
+package pipbot
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSpeedLayers(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		deck, class Speeds
+		want        Speeds
+		approach    float64
+	}{
+		{name: "defaults", want: DefaultSpeeds, approach: DefaultSpeeds.Z},
+		{name: "deck", deck: Speeds{XY: 3000, Plunger: 400}, want: Speeds{XY: 3000, Z: 1000, Plunger: 400}, approach: 1000},
+		{
+			name:     "class over deck",
+			deck:     Speeds{XY: 3000, Z: 600, Approach: 300},
+			class:    Speeds{Z: 200, Approach: 50},
+			want:     Speeds{XY: 3000, Z: 200, Approach: 50},
+			approach: 50,
+		},
+		{name: "class over defaults", class: Speeds{Z: 200}, want: Speeds{XY: 1000, Z: 200}, approach: 200},
+		{
+			name:     "accelerations",
+			deck:     Speeds{Acceleration: 500, AxisAcceleration: Axes{X: 800, Z: 100}},
+			class:    Speeds{AxisAcceleration: Axes{Z: 50}},
+			want:     Speeds{XY: 1000, Z: 1000, Acceleration: 500, AxisAcceleration: Axes{X: 800, Z: 50}},
+			approach: 1000,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := DefaultSpeeds.with(tc.deck).with(tc.class)
+			if got != tc.want {
+				t.Errorf("got %+v\nwant %+v", got, tc.want)
+			}
+			if a := got.approach(); a != tc.approach {
+				t.Errorf("approach %v, want %v", a, tc.approach)
+			}
+		})
+	}
+}
+
+// feeds lists the feed rates of the moves across and the moves up and down
+// in lines.
+func feeds(lines [][]byte) (xy, z []float64) {
+	seen := map[string]bool{}
+	for _, l := range lines {
+		var f float64
+		var axis string
+		if _, err := fmt.Sscanf(string(l), "G0 F%v %1s", &f, &axis); err != nil {
+			continue
+		}
+		if key := fmt.Sprint(axis, f); !seen[key] {
+			seen[key] = true
+			if axis == "X" {
+				xy = append(xy, f)
+			} else {
+				z = append(z, f)
+			}
+		}
+	}
+	sort.Float64s(xy)
+	sort.Float64s(z)
+	return xy, z
+}
+
+func TestTransferSpeedLayers(t *testing.T) {
+	lw, err := LookupLabware("opentrons_24_tuberack_eppendorf_1.5ml_safelock_snapcap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := lw.At("tubes", Position{X: 50, Y: 50, Z: 80})
+	src, dest := m.Cells[0][0], m.Cells[0][1]
+	tip := Position{X: 150, Y: 50, Z: 60}
+	tr := NewTransfer(StockPipette(), &tip, src.Position, dest.Position, 50, true)
+	tr.src, tr.dest = src, dest
+	tr.speeds = Speeds{XY: 3000, Z: 600}
+	tr.Class = &LiquidClass{Name: "slow", Speeds: Speeds{Z: 200, Approach: 50}}
+	phases, _, err := tr.phases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[Phase][2][]float64{
+		// the deck's speeds away from the liquid, the class's in it
+		PickingTip: {{3000}, {600}},
+		Aspirating: {{3000}, {50, 200}},
+		Dispensing: {{3000}, {50, 200}},
+		Ejecting:   {{3000}, {600}},
+	}
+	for _, p := range phases {
+		w, ok := want[p.Phase]
+		if !ok {
+			continue
+		}
+		xy, z := feeds(p.lines)
+		if !reflect.DeepEqual(xy, w[0]) || !reflect.DeepEqual(z, w[1]) {
+			t.Errorf("%v: feeds %v across and %v up and down, want %v and %v:\n%s",
+				p.Phase, xy, z, w[0], w[1], bytes.Join(p.lines, nil))
+		}
+		delete(want, p.Phase)
+	}
+	for p := range want {
+		t.Errorf("no %v phase", p)
+	}
+}
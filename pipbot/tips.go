@@ -0,0 +1,123 @@
+package pipbot
+
+import (
+	"pipbot/graph/model"
+)
+
+// TipPolicy says when transfers get a fresh tip. Whatever the policy, a tip
+// that has drawn from one source is never used to draw from another.
+type TipPolicy string
+
+const (
+	// TipAlways takes a fresh tip for every transfer.
+	TipAlways TipPolicy = "always"
+	// TipNever keeps the tip for as long as the source stays the same, in the
+	// order the transfers were given.
+	TipNever TipPolicy = "never"
+	// TipPerSource uses one tip for all the transfers from a source.
+	TipPerSource TipPolicy = "per-source"
+	// TipPerDestination uses one tip for the transfers from a source into the
+	// same destination well, such as a volume split over several trips.
+	TipPerDestination TipPolicy = "per-destination"
+	// TipPerGroup uses one tip for the transfers from a source that share a
+	// group. Transfers without a group get a tip each.
+	TipPerGroup TipPolicy = "per-group"
+)
+
+// DefaultTipPolicy is the policy of transfers that do not set one when the
+// bot's TipPolicy is empty.
+const DefaultTipPolicy = TipPerSource
+
+func (p TipPolicy) valid() bool {
+	switch p {
+	case TipAlways, TipNever, TipPerSource, TipPerDestination, TipPerGroup:
+		return true
+	}
+	return false
+}
+
+// groups reports whether transfers with p may be moved next to others they
+// can share a tip with. Always and never keep the given order.
+func (p TipPolicy) groups() bool {
+	return p == TipPerSource || p == TipPerDestination || p == TipPerGroup
+}
+
+// planned is the n'th transfer of a recipe resolved against the deck.
+type planned struct {
+	n         int
+	t         *model.Transfer
+	src, dest *Cell
+	policy    TipPolicy
+	mixBefore *Mix
+	mixAfter  *Mix
+	class     *LiquidClass
+}
+
+// shares reports whether p and the transfer right after it, n, can use the
+// same tip. A tip that has mixed in a destination, or is about to mix a
+// source after dispensing, has been in liquid other than the source's and is
+// never taken back to it.
+func (p planned) shares(n planned) bool {
+	if p.src != n.src || p.policy != n.policy {
+		return false
+	}
+	if p.mixAfter != nil || n.mixBefore != nil {
+		return false
+	}
+	switch p.policy {
+	case TipNever, TipPerSource:
+		return true
+	case TipPerDestination:
+		return p.dest == n.dest
+	case TipPerGroup:
+		return p.t.Group != nil && n.t.Group != nil && *p.t.Group == *n.t.Group
+	}
+	return false
+}
+
+// after reports whether p has to stay after e because they touch the same
+// well and at least one of them dispenses into it.
+func (p planned) after(e planned) bool {
+	return e.dest == p.src || e.dest == p.dest || p.dest == e.src
+}
+
+// reorder moves transfers that can share a tip next to each other, if their
+// policy lets them be moved. It keeps to the given order as far as it can, and
+// never moves a transfer ahead of an earlier one it depends on.
+func reorder(plan []planned) []planned {
+	waiting := make([]int, len(plan))
+	blocks := make([][]int, len(plan))
+	for i := range plan {
+		for j := 0; j < i; j++ {
+			if plan[i].after(plan[j]) {
+				waiting[i]++
+				blocks[j] = append(blocks[j], i)
+			}
+		}
+	}
+	done := make([]bool, len(plan))
+	res := make([]planned, 0, len(plan))
+	last := -1
+	for len(res) < len(plan) {
+		next := -1
+		for i := range plan {
+			if done[i] || waiting[i] > 0 {
+				continue
+			}
+			if last < 0 || plan[i].policy.groups() && plan[last].shares(plan[i]) {
+				next = i
+				break
+			}
+			if next < 0 {
+				next = i
+			}
+		}
+		done[next] = true
+		for _, i := range blocks[next] {
+			waiting[i]--
+		}
+		res = append(res, plan[next])
+		last = next
+	}
+	return res
+}
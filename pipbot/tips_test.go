@@ -0,0 +1,104 @@
+package pipbot
+
+import (
+	"reflect"
+	"testing"
+
+	"pipbot/graph/model"
+)
+
+// order returns the n of each transfer in plan.
+func order(plan []planned) []int {
+	res := make([]int, len(plan))
+	for i, p := range plan {
+		res[i] = p.n
+	}
+	return res
+}
+
+func TestReorderByTransferPolicy(t *testing.T) {
+	a, b := &Cell{}, &Cell{}
+	dest := func() *Cell { return &Cell{} }
+	for _, tc := range []struct {
+		name     string
+		policies []TipPolicy
+		src      []*Cell
+		want     []int
+	}{
+		{
+			name:     "per-source overrides are grouped under always",
+			policies: []TipPolicy{TipAlways, TipPerSource, TipAlways, TipPerSource},
+			src:      []*Cell{a, b, a, b},
+			want:     []int{0, 1, 3, 2},
+		},
+		{
+			name:     "never keeps the given order",
+			policies: []TipPolicy{TipNever, TipNever, TipNever},
+			src:      []*Cell{a, b, a},
+			want:     []int{0, 1, 2},
+		},
+		{
+			name:     "per-source",
+			policies: []TipPolicy{TipPerSource, TipPerSource, TipPerSource},
+			src:      []*Cell{a, b, a},
+			want:     []int{0, 2, 1},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			plan := make([]planned, len(tc.policies))
+			for i, p := range tc.policies {
+				plan[i] = planned{n: i, t: &model.Transfer{}, src: tc.src[i], dest: dest(), policy: p}
+			}
+			if got := order(reorder(plan)); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("order %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSharesNotAfterMixing(t *testing.T) {
+	src := &Cell{}
+	mix := &Mix{Cycles: 3, Volume: 20}
+	for _, tc := range []struct {
+		name                string
+		mixAfter, mixBefore *Mix
+		want                bool
+	}{
+		{name: "no mixing", want: true},
+		{name: "mixed in the destination", mixAfter: mix},
+		{name: "mixes the source next", mixBefore: mix},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := planned{t: &model.Transfer{}, src: src, dest: &Cell{}, policy: TipPerSource, mixAfter: tc.mixAfter}
+			n := planned{n: 1, t: &model.Transfer{}, src: src, dest: &Cell{}, policy: TipPerSource, mixBefore: tc.mixBefore}
+			if got := p.shares(n); got != tc.want {
+				t.Errorf("shares = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPlanFreshTipAfterMixing(t *testing.T) {
+	b := NewPipBotOn(NewSimulator(nil))
+	if err := b.Init(); err != nil {
+		t.Fatal(err)
+	}
+	mix := &model.Mix{Cycles: 2, Volume: 20}
+	r := &Recipe{Transfers: []*model.Transfer{
+		{Source: &model.Node{Grid: "12", Position: "A1"}, Dest: &model.Node{Grid: "96", Position: "A1"}, Volume: 50, MixAfter: mix},
+		{Source: &model.Node{Grid: "12", Position: "A1"}, Dest: &model.Node{Grid: "96", Position: "A2"}, Volume: 50},
+	}}
+	actions, err := b.PlanRecipe(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tips := 0
+	for _, a := range actions {
+		if tr, ok := a.(*Transfer); ok && tr.Tip != nil {
+			tips++
+		}
+	}
+	if tips != 2 {
+		t.Errorf("%v tips for two transfers from one source, the first mixing after", tips)
+	}
+}
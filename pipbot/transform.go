@@ -0,0 +1,125 @@
+package pipbot
+
+import (
+	"errors"
+	"math"
+)
+
+// Transform is an affine map from the grid of a matrix onto the bed. The cell
+// at row, col sits at Origin + col*Col + row*Row, so Col and Row are the steps
+// to the next well along a row and down a column. Their Z parts tilt the
+// matrix, which a plate that is not sitting flat needs.
+type Transform struct {
+	Origin Position `yaml:"origin" json:"origin"`
+	Col    Position `yaml:"col" json:"col"`
+	Row    Position `yaml:"row" json:"row"`
+}
+
+// Apply returns where the cell at row, col is.
+func (t Transform) Apply(row, col int) Position {
+	r, c := float32(row), float32(col)
+	return Position{
+		X: t.Origin.X + c*t.Col.X + r*t.Row.X,
+		Y: t.Origin.Y + c*t.Col.Y + r*t.Row.Y,
+		Z: t.Origin.Z + c*t.Col.Z + r*t.Row.Z,
+	}
+}
+
+// Rotation is the angle of the rows from the X axis and Skew how far the
+// columns lean from square to them, in degrees, as Matrix has them.
+func (t Transform) Rotation() (rotation, skew float32) {
+	theta := math.Atan2(float64(t.Col.Y), float64(t.Col.X))
+	psi := math.Atan2(-float64(t.Row.X), float64(t.Row.Y))
+	return degrees(theta), degrees(psi - theta)
+}
+
+// Spacing is the distance across the bed between neighbouring wells down a
+// column and along a row.
+func (t Transform) Spacing() (rowSpace, colSpace float32) {
+	return hundredths(float32(math.Hypot(float64(t.Row.X), float64(t.Row.Y)))),
+		hundredths(float32(math.Hypot(float64(t.Col.X), float64(t.Col.Y))))
+}
+
+// degrees converts rad to degrees in (-180, 180], rounded to 0.01.
+func degrees(rad float64) float32 {
+	d := math.Mod(rad*180/math.Pi, 360)
+	switch {
+	case d > 180:
+		d -= 360
+	case d <= -180:
+		d += 360
+	}
+	return hundredths(float32(d))
+}
+
+// GridPoint is where a cell was measured to be.
+type GridPoint struct {
+	Row, Col int
+	At       Position
+}
+
+// FitTransform finds the transform that puts the cells of points where they
+// were measured. Three wells that are not in a line, such as three corners of
+// a plate, fix it exactly; more are fitted by least squares.
+func FitTransform(points []GridPoint) (Transform, error) {
+	if len(points) < 3 {
+		return Transform{}, errors.New("a transform needs three measured wells")
+	}
+	// normal equations of [1 col row] * [origin col row] = at
+	var a [3][3]float64
+	var b [3][3]float64
+	for _, p := range points {
+		v := [3]float64{1, float64(p.Col), float64(p.Row)}
+		at := [3]float64{float64(p.At.X), float64(p.At.Y), float64(p.At.Z)}
+		for i := range v {
+			for j := range v {
+				a[i][j] += v[i] * v[j]
+			}
+			for k := range at {
+				b[i][k] += v[i] * at[k]
+			}
+		}
+	}
+	x, ok := solve3(a, b)
+	if !ok {
+		return Transform{}, errors.New("the measured wells are in a line, measure three corners")
+	}
+	vec := func(i int) Position {
+		return Position{X: hundredths(float32(x[i][0])), Y: hundredths(float32(x[i][1])), Z: hundredths(float32(x[i][2]))}
+	}
+	return Transform{Origin: vec(0), Col: vec(1), Row: vec(2)}, nil
+}
+
+// solve3 solves a*x = b for x by Gaussian elimination with partial pivoting.
+// Each column of b is a separate right hand side.
+func solve3(a, b [3][3]float64) ([3][3]float64, bool) {
+	for c := 0; c < 3; c++ {
+		p := c
+		for r := c + 1; r < 3; r++ {
+			if math.Abs(a[r][c]) > math.Abs(a[p][c]) {
+				p = r
+			}
+		}
+		if math.Abs(a[p][c]) < 1e-9 {
+			return b, false
+		}
+		a[c], a[p] = a[p], a[c]
+		b[c], b[p] = b[p], b[c]
+		for r := 0; r < 3; r++ {
+			if r == c {
+				continue
+			}
+			f := a[r][c] / a[c][c]
+			for k := 0; k < 3; k++ {
+				a[r][k] -= f * a[c][k]
+				b[r][k] -= f * b[c][k]
+			}
+		}
+	}
+	for r := 0; r < 3; r++ {
+		for k := 0; k < 3; k++ {
+			b[r][k] /= a[r][r]
+		}
+	}
+	return b, true
+}
@@ -0,0 +1,100 @@
+package pipbot
+
+import (
+	"math"
+	"testing"
+)
+
+// skewedPlate is a 96 well plate turned 2° on the bed with its columns
+// leaning another 1°, tilted a little in both directions.
+func skewedPlate() Transform {
+	theta, psi := 2*math.Pi/180, 3*math.Pi/180
+	return Transform{
+		Origin: Position{X: 14.38, Y: 11.24, Z: 3},
+		Col:    Position{X: float32(9 * math.Cos(theta)), Y: float32(9 * math.Sin(theta)), Z: 0.01},
+		Row:    Position{X: float32(-9 * math.Sin(psi)), Y: float32(9 * math.Cos(psi)), Z: -0.02},
+	}
+}
+
+func TestFitTransform(t *testing.T) {
+	plate := skewedPlate()
+	corners := []GridPoint{
+		{Row: 0, Col: 0, At: plate.Apply(0, 0)},
+		{Row: 0, Col: 11, At: plate.Apply(0, 11)},
+		{Row: 7, Col: 0, At: plate.Apply(7, 0)},
+	}
+	// measured by eye, so off by up to 0.05 mm either way
+	noisy := append(corners[:3:3],
+		GridPoint{Row: 7, Col: 11, At: plate.Apply(7, 11).Add(0.05, -0.03, 0)},
+		GridPoint{Row: 3, Col: 5, At: plate.Apply(3, 5).Add(-0.04, 0.05, 0.02)},
+		GridPoint{Row: 4, Col: 8, At: plate.Apply(4, 8).Add(0.02, -0.05, -0.03)},
+	)
+	for _, tc := range []struct {
+		name   string
+		points []GridPoint
+		tol    float32
+	}{
+		{name: "three corners", points: corners, tol: 0.01},
+		{name: "noisy extra wells", points: noisy, tol: 0.03},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := FitTransform(tc.points)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, v := range []struct {
+				name      string
+				got, want Position
+			}{{"origin", got.Origin, plate.Origin}, {"col", got.Col, plate.Col}, {"row", got.Row, plate.Row}} {
+				if !near(v.got.X, v.want.X, tc.tol) || !near(v.got.Y, v.want.Y, tc.tol) || !near(v.got.Z, v.want.Z, tc.tol) {
+					t.Errorf("%v %v, want %v", v.name, v.got, v.want)
+				}
+			}
+			rot, skew := got.Rotation()
+			if !near(rot, 2, 0.05) || !near(skew, 1, 0.05) {
+				t.Errorf("rotation %v skew %v, want 2 and 1", rot, skew)
+			}
+			rowSpace, colSpace := got.Spacing()
+			if !near(rowSpace, 9, tc.tol) || !near(colSpace, 9, tc.tol) {
+				t.Errorf("spacing %v by %v, want 9 by 9", rowSpace, colSpace)
+			}
+		})
+	}
+}
+
+func TestFitTransformErrors(t *testing.T) {
+	plate := skewedPlate()
+	for _, tc := range []struct {
+		name   string
+		points []GridPoint
+		want   string
+	}{
+		{
+			name:   "two wells",
+			points: []GridPoint{{At: plate.Apply(0, 0)}, {Col: 11, At: plate.Apply(0, 11)}},
+			want:   "a transform needs three measured wells",
+		},
+		{
+			name: "along a row",
+			points: []GridPoint{
+				{At: plate.Apply(0, 0)}, {Col: 5, At: plate.Apply(0, 5)}, {Col: 11, At: plate.Apply(0, 11)},
+			},
+			want: "the measured wells are in a line, measure three corners",
+		},
+		{
+			name: "diagonal",
+			points: []GridPoint{
+				{At: plate.Apply(0, 0)}, {Row: 3, Col: 3, At: plate.Apply(3, 3)},
+				{Row: 7, Col: 7, At: plate.Apply(7, 7)}, {Row: 5, Col: 5, At: plate.Apply(5, 5)},
+			},
+			want: "the measured wells are in a line, measure three corners",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := FitTransform(tc.points)
+			if err == nil || err.Error() != tc.want {
+				t.Errorf("got %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package pipbot
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Transport is the byte stream between a PipBot and the printer firmware.
+type Transport interface {
+	io.ReadWriteCloser
+}
+
+// fileTransport writes the G-code stream to a file instead of a printer. There
+// is no firmware on the other end, so reads always report io.EOF.
+type fileTransport struct {
+	*os.File
+}
+
+func (f *fileTransport) Read([]byte) (int, error) {
+	return 0, io.EOF
+}
+
+// NewFileTransport creates (or truncates) the named file and returns a
+// Transport that writes every command to it.
+func NewFileTransport(name string) (Transport, error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return &fileTransport{File: f}, nil
+}
+
+// Open returns the Transport for port. Ports ending in ".gcode" are treated as
+// output files, anything else as a serial device running at baud with Marlin
+// flow control on top.
+func Open(port string, baud int) (Transport, error) {
+	if filepath.Ext(port) == ".gcode" {
+		return NewFileTransport(port)
+	}
+	t, err := NewSerial(port, baud)
+	if err != nil {
+		return nil, err
+	}
+	return NewMarlin(t), nil
+}